@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Rule is a check run over a whole program
+type Rule struct {
+	Name string
+	Doc  string
+	Run  func(*Pass)
+}
+
+//Pass is what a Rule gets to look at and report on
+type Pass struct {
+	Program  *ast.Program
+	Builtins map[string]bool
+
+	rule        *Rule
+	diagnostics []Diagnostic
+}
+
+//Reportf records a finding at the position of tok
+func (p *Pass) Reportf(tok token.Token, format string, a ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Rule:    p.rule.Name,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+//Diagnostic is a finding of a rule
+type Diagnostic struct {
+	Rule    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+//Rules are all the rules, enabled by default
+var Rules = []*Rule{
+	Unused,
+	BuiltinShadow,
+	Unreachable,
+	ConstantCondition,
+	CallNonFunction,
+	DuplicateKey,
+}
+
+//Lookup returns the rule called name
+func Lookup(name string) (*Rule, bool) {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+//Config selects the rules to run. A rule runs unless Disabled names it, and
+//when Enabled is not empty, only if Enabled names it.
+type Config struct {
+	Enabled  []string
+	Disabled []string
+}
+
+//Rules returns the rules c selects, or an error for a name that is not a rule
+func (c Config) Rules() ([]*Rule, error) {
+	for _, name := range append(append([]string{}, c.Enabled...), c.Disabled...) {
+		if _, ok := Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+
+	rules := []*Rule{}
+	for _, rule := range Rules {
+		if (len(c.Enabled) == 0 || contains(c.Enabled, rule.Name)) && !contains(c.Disabled, rule.Name) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+//Run runs rules over program and returns their findings sorted by position.
+//builtins are the names the program can use without declaring them.
+func Run(program *ast.Program, rules []*Rule, builtins []string) []Diagnostic {
+	names := make(map[string]bool)
+	for _, name := range builtins {
+		names[name] = true
+	}
+
+	diagnostics := []Diagnostic{}
+	for _, rule := range rules {
+		pass := &Pass{Program: program, Builtins: names, rule: rule}
+		rule.Run(pass)
+		diagnostics = append(diagnostics, pass.diagnostics...)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return diagnostics
+}
@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func runRule(t *testing.T, rule *Rule, input string) []string {
+	found := []string{}
+	for _, d := range Run(parse(t, input), []*Rule{rule}, []string{"len", "puts"}) {
+		found = append(found, d.String())
+	}
+	return found
+}
+
+func testRule(t *testing.T, rule *Rule, tests []struct {
+	input    string
+	expected []string
+}) {
+	for _, tt := range tests {
+		found := runRule(t, rule, tt.input)
+		if len(found) != len(tt.expected) {
+			t.Errorf("%s: wrong findings for %q. want=%q, got=%q", rule.Name, tt.input, tt.expected, found)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if found[i] != msg {
+				t.Errorf("%s: finding %d for %q wrong. want=%q, got=%q", rule.Name, i, tt.input, msg, found[i])
+			}
+		}
+	}
+}
+
+func TestUnused(t *testing.T) {
+	testRule(t, Unused, []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1;", []string{}},
+		{"fn(x) { x }", []string{}},
+		{"fn(x, y) { x }", []string{"1:7: parameter y is never used (unused)"}},
+		{"fn() { let a = 1; 2 }", []string{"1:12: a declared and not used (unused)"}},
+		{"fn() { let a = 1; fn() { a } }", []string{}},
+		{"fn() { let f = fn() { f() }; }", []string{}},
+		{"fn(_x) { let _y = 1; }", []string{}},
+		{"fn() { try { 1 } catch (e) { 2 } }", []string{}},
+		{"fn(x) { quote(x) }", []string{"1:4: parameter x is never used (unused)"}},
+		{"fn(x) { quote(unquote(x)) }", []string{}},
+		{"fn(x) { let o = {}; o.x }", []string{"1:4: parameter x is never used (unused)"}},
+	})
+}
+
+func TestBuiltinShadow(t *testing.T) {
+	testRule(t, BuiltinShadow, []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1;", []string{}},
+		{"let len = 1;", []string{"1:5: len shadows a builtin (builtin-shadow)"}},
+		{"const puts = 1;", []string{"1:7: puts shadows a builtin (builtin-shadow)"}},
+		{"fn(len) { len }", []string{"1:4: len shadows a builtin (builtin-shadow)"}},
+		{"try { 1 } catch (puts) { 2 }", []string{"1:18: puts shadows a builtin (builtin-shadow)"}},
+		{"len([1]);", []string{}},
+	})
+}
+
+func TestUnreachable(t *testing.T) {
+	testRule(t, Unreachable, []struct {
+		input    string
+		expected []string
+	}{
+		{"return 1;", []string{}},
+		{"return 1; 2;", []string{"1:11: unreachable statement after return (unreachable)"}},
+		{"fn() { throw 1; let x = 2; x }", []string{"1:17: unreachable statement after throw (unreachable)"}},
+		{"fn() { if (true) { return 1 }; 2 }", []string{}},
+	})
+}
+
+func TestConstantCondition(t *testing.T) {
+	testRule(t, ConstantCondition, []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; if (x) { 1 }", []string{}},
+		{"if (true) { 1 }", []string{"1:1: if condition true is constant (constant-condition)"}},
+		{"if (1 < 2) { 1 }", []string{"1:1: if condition (1 < 2) is constant (constant-condition)"}},
+		{"if (!false) { 1 }", []string{"1:1: if condition (!false) is constant (constant-condition)"}},
+		{"let x = 1; if (x < 2) { 1 }", []string{}},
+	})
+}
+
+func TestCallNonFunction(t *testing.T) {
+	testRule(t, CallNonFunction, []struct {
+		input    string
+		expected []string
+	}{
+		{"let f = fn() { 1 }; f()", []string{}},
+		{"fn() { 1 }()", []string{}},
+		{"1(2)", []string{"1:1: cannot call integer literal 1 (call-non-function)"}},
+		{`"a"()`, []string{`1:1: cannot call string literal "a" (call-non-function)`}},
+		{"[1](0)", []string{"1:1: cannot call array literal (call-non-function)"}},
+		{"(1..3)(0)", []string{"1:7: cannot call range literal (call-non-function)"}},
+	})
+}
+
+func TestDuplicateKey(t *testing.T) {
+	testRule(t, DuplicateKey, []struct {
+		input    string
+		expected []string
+	}{
+		{`{"a": 1, "b": 2}`, []string{}},
+		{`{"a": 1, "a": 2}`, []string{`1:10: duplicate key "a" in hash literal (duplicate-key)`}},
+		{`{1: 1, true: 2, 1: 3}`, []string{`1:17: duplicate key 1 in hash literal (duplicate-key)`}},
+		{`let k = "a"; {k: 1, k: 2}`, []string{}},
+	})
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		config   Config
+		expected []string
+	}{
+		{Config{}, []string{"unused", "builtin-shadow", "unreachable", "constant-condition", "call-non-function", "duplicate-key"}},
+		{Config{Enabled: []string{"unused", "unreachable"}}, []string{"unused", "unreachable"}},
+		{Config{Disabled: []string{"unused", "duplicate-key"}}, []string{"builtin-shadow", "unreachable", "constant-condition", "call-non-function"}},
+		{Config{Enabled: []string{"unused"}, Disabled: []string{"unused"}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		rules, err := tt.config.Rules()
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", tt.config, err)
+		}
+		if len(rules) != len(tt.expected) {
+			t.Errorf("%+v: wrong number of rules. want=%d, got=%d", tt.config, len(tt.expected), len(rules))
+			continue
+		}
+		for i, name := range tt.expected {
+			if rules[i].Name != name {
+				t.Errorf("%+v: rule %d wrong. want=%s, got=%s", tt.config, i, name, rules[i].Name)
+			}
+		}
+	}
+
+	_, err := Config{Disabled: []string{"nope"}}.Rules()
+	if err == nil || err.Error() != `unknown rule "nope"` {
+		t.Errorf("wrong error for an unknown rule. got=%v", err)
+	}
+}
+
+func TestRunSortsByPosition(t *testing.T) {
+	input := `let len = fn(x) {
+	return 1;
+	{"a": 1, "a": 2};
+};`
+	found := []string{}
+	for _, d := range Run(parse(t, input), Rules, []string{"len"}) {
+		found = append(found, d.String())
+	}
+
+	expected := []string{
+		"1:5: len shadows a builtin (builtin-shadow)",
+		"1:14: parameter x is never used (unused)",
+		"3:2: unreachable statement after return (unreachable)",
+		"3:11: duplicate key \"a\" in hash literal (duplicate-key)",
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("wrong findings. want=%q, got=%q", expected, found)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Errorf("finding %d wrong. want=%q, got=%q", i, expected[i], found[i])
+		}
+	}
+}
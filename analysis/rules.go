@@ -0,0 +1,370 @@
+package analysis
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Unused reports local let bindings and parameters that are never used. Names
+//starting with _ are ignored, as are globals, which a module may export.
+var Unused = &Rule{
+	Name: "unused",
+	Doc:  "let bindings and parameters of functions that are never used",
+	Run:  runUnused,
+}
+
+//BuiltinShadow reports declarations hiding a builtin, e.g. let len = ...
+var BuiltinShadow = &Rule{
+	Name: "builtin-shadow",
+	Doc:  "declarations that hide a builtin function",
+	Run:  runBuiltinShadow,
+}
+
+//Unreachable reports statements following a return or throw in the same block
+var Unreachable = &Rule{
+	Name: "unreachable",
+	Doc:  "statements after a return or throw",
+	Run:  runUnreachable,
+}
+
+//ConstantCondition reports if expressions that always take the same branch
+var ConstantCondition = &Rule{
+	Name: "constant-condition",
+	Doc:  "if conditions made of literals only",
+	Run:  runConstantCondition,
+}
+
+//CallNonFunction reports calls of literals that cannot be functions
+var CallNonFunction = &Rule{
+	Name: "call-non-function",
+	Doc:  "calls of integer, string, boolean, array, hash or range literals",
+	Run:  runCallNonFunction,
+}
+
+//DuplicateKey reports hash literals with the same literal key twice. Only the
+//last value of such a key is kept.
+var DuplicateKey = &Rule{
+	Name: "duplicate-key",
+	Doc:  "hash literals that repeat a key",
+	Run:  runDuplicateKey,
+}
+
+type binding struct {
+	ident *ast.Identifier
+	kind  string
+	used  bool
+}
+
+type scope struct {
+	outer    *scope
+	names    map[string]*binding
+	bindings []*binding
+}
+
+//unusedChecker tracks scopes the way the evaluator creates environments: one
+//per function call and catch block, with lets visible in the whole scope
+type unusedChecker struct {
+	pass  *Pass
+	scope *scope
+}
+
+func runUnused(pass *Pass) {
+	c := &unusedChecker{pass: pass}
+	c.scope = &scope{names: make(map[string]*binding)}
+	c.hoist(pass.Program)
+	ast.Inspect(pass.Program, c.visit)
+}
+
+func (c *unusedChecker) openScope() {
+	c.scope = &scope{outer: c.scope, names: make(map[string]*binding)}
+}
+
+//closeScope reports the unused names of the innermost scope and leaves it
+func (c *unusedChecker) closeScope() {
+	for _, b := range c.scope.bindings {
+		if b.used || b.kind == "catch" || strings.HasPrefix(b.ident.Value, "_") {
+			continue
+		}
+		if b.kind == "parameter" {
+			c.pass.Reportf(b.ident.Token, "parameter %s is never used", b.ident.Value)
+		} else {
+			c.pass.Reportf(b.ident.Token, "%s declared and not used", b.ident.Value)
+		}
+	}
+	c.scope = c.scope.outer
+}
+
+func (c *unusedChecker) declare(ident *ast.Identifier, kind string) {
+	if _, ok := c.scope.names[ident.Value]; ok {
+		return
+	}
+	b := &binding{ident: ident, kind: kind}
+	c.scope.names[ident.Value] = b
+	c.scope.bindings = append(c.scope.bindings, b)
+}
+
+func (c *unusedChecker) use(name string) {
+	for s := c.scope; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			b.used = true
+			return
+		}
+	}
+}
+
+func (c *unusedChecker) hoist(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			c.declare(node.Name, "let")
+		case *ast.ConstStatement:
+			c.declare(node.Name, "let")
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.TryExpression:
+			c.hoist(node.Block)
+			if node.Finally != nil {
+				c.hoist(node.Finally)
+			}
+			return false
+		case *ast.CallExpression:
+			return !isCallTo(node, "quote")
+		}
+		return true
+	})
+}
+
+func (c *unusedChecker) visit(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		ast.Inspect(node.Value, c.visit)
+		return false
+	case *ast.ConstStatement:
+		ast.Inspect(node.Value, c.visit)
+		return false
+	case *ast.Identifier:
+		c.use(node.Value)
+	case *ast.MemberExpression:
+		ast.Inspect(node.Object, c.visit)
+		return false
+	case *ast.FunctionLiteral:
+		c.function(node.Parameters, node.Body)
+		return false
+	case *ast.MacroLiteral:
+		c.function(node.Parameters, node.Body)
+		return false
+	case *ast.TryExpression:
+		ast.Inspect(node.Block, c.visit)
+		if node.Catch != nil {
+			c.openScope()
+			c.declare(node.CatchParam, "catch")
+			c.hoist(node.Catch)
+			ast.Inspect(node.Catch, c.visit)
+			c.closeScope()
+		}
+		if node.Finally != nil {
+			ast.Inspect(node.Finally, c.visit)
+		}
+		return false
+	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			c.quoted(node)
+			return false
+		}
+	}
+	return true
+}
+
+func (c *unusedChecker) function(params []*ast.Identifier, body *ast.BlockStatement) {
+	c.openScope()
+	for _, param := range params {
+		c.declare(param, "parameter")
+	}
+	c.hoist(body)
+	ast.Inspect(body, c.visit)
+	c.closeScope()
+}
+
+//quoted marks the names used by the unquote calls of a quote call
+func (c *unusedChecker) quoted(node *ast.CallExpression) {
+	for _, arg := range node.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpression); ok && isCallTo(call, "unquote") {
+				for _, arg := range call.Arguments {
+					ast.Inspect(arg, c.visit)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+func runBuiltinShadow(pass *Pass) {
+	check := func(ident *ast.Identifier) {
+		if ident != nil && pass.Builtins[ident.Value] {
+			pass.Reportf(ident.Token, "%s shadows a builtin", ident.Value)
+		}
+	}
+
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			check(node.Name)
+		case *ast.ConstStatement:
+			check(node.Name)
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				check(param)
+			}
+		case *ast.MacroLiteral:
+			for _, param := range node.Parameters {
+				check(param)
+			}
+		case *ast.TryExpression:
+			check(node.CatchParam)
+		}
+		return true
+	})
+}
+
+func runUnreachable(pass *Pass) {
+	check := func(stmts []ast.Statement) {
+		for i, stmt := range stmts[:max(len(stmts)-1, 0)] {
+			switch stmt.(type) {
+			case *ast.ReturnStatement:
+				pass.Reportf(statementToken(stmts[i+1]), "unreachable statement after return")
+				return
+			case *ast.ThrowStatement:
+				pass.Reportf(statementToken(stmts[i+1]), "unreachable statement after throw")
+				return
+			}
+		}
+	}
+
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			check(node.Statements)
+		case *ast.BlockStatement:
+			check(node.Statements)
+		}
+		return true
+	})
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ConstStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ThrowStatement:
+		return stmt.Token
+	case *ast.DeferStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.BadStatement:
+		return stmt.From
+	}
+	return token.Token{}
+}
+
+func runConstantCondition(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		if ie, ok := node.(*ast.IfExpression); ok && isConstant(ie.Condition) {
+			pass.Reportf(ie.Token, "if condition %s is constant", ie.Condition.String())
+		}
+		return true
+	})
+}
+
+//isConstant reports whether exp is made of literals only. Array, hash and
+//function literals are always truthy, whatever they contain.
+func isConstant(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(exp.Right)
+	case *ast.InfixExpression:
+		return isConstant(exp.Left) && isConstant(exp.Right)
+	}
+	return false
+}
+
+func runCallNonFunction(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+
+		switch fn := call.Function.(type) {
+		case *ast.IntegerLiteral:
+			pass.Reportf(fn.Token, "cannot call integer literal %s", fn.String())
+		case *ast.StringLiteral:
+			pass.Reportf(fn.Token, "cannot call string literal %q", fn.Value)
+		case *ast.Boolean:
+			pass.Reportf(fn.Token, "cannot call boolean literal %s", fn.String())
+		case *ast.ArrayLiteral:
+			pass.Reportf(fn.Token, "cannot call array literal")
+		case *ast.HashLiteral:
+			pass.Reportf(fn.Token, "cannot call hash literal")
+		case *ast.RangeExpression:
+			pass.Reportf(call.Token, "cannot call range literal")
+		}
+		return true
+	})
+}
+
+func runDuplicateKey(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		hash, ok := node.(*ast.HashLiteral)
+		if !ok {
+			return true
+		}
+
+		seen := make(map[string]bool)
+		for _, pair := range hash.Pairs {
+			var key string
+			var tok token.Token
+			switch k := pair.Key.(type) {
+			case *ast.IntegerLiteral:
+				key, tok = strconv.FormatInt(k.Value, 10), k.Token
+			case *ast.StringLiteral:
+				key, tok = strconv.Quote(k.Value), k.Token
+			case *ast.Boolean:
+				key, tok = strconv.FormatBool(k.Value), k.Token
+			default:
+				continue
+			}
+
+			if seen[key] {
+				pass.Reportf(tok, "duplicate key %s in hash literal", key)
+			}
+			seen[key] = true
+		}
+		return true
+	})
+}
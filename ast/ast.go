@@ -48,10 +48,37 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+//BadStatement is a placeholder for a statement that failed to parse, spanning
+//the tokens From to To
+type BadStatement struct {
+	From token.Token
+	To   token.Token
+}
+
+func (bs *BadStatement) statementNode() {}
+
+//TokenLiteral ...
+func (bs *BadStatement) TokenLiteral() string { return bs.From.Literal }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+
+//BadExpression is a placeholder for an expression that failed to parse,
+//spanning the tokens From to To
+type BadExpression struct {
+	From token.Token
+	To   token.Token
+}
+
+func (be *BadExpression) expressionNode() {}
+
+//TokenLiteral ...
+func (be *BadExpression) TokenLiteral() string { return be.From.Literal }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+
 //LetStatement ...
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  *TypeAnnotation // nil unless written as let x: int = ...
 	Value Expression
 }
 
@@ -64,6 +91,9 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -72,10 +102,42 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+//ConstStatement binds a name that cannot be rebound in the same scope
+type ConstStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (cs *ConstStatement) statementNode() {}
+
+//TokenLiteral ...
+func (cs *ConstStatement) TokenLiteral() string { return cs.Token.Literal }
+
+func (cs *ConstStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 //Identifier ...
 type Identifier struct {
-	Token token.Token
-	Value string
+	Token   token.Token
+	Value   string
+	Binding *Binding // set by the resolver for local variables, nil otherwise
+}
+
+//Binding locates a local variable: Slot in the scope Depth levels out from
+//the scope the identifier appears in
+type Binding struct {
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expressionNode() {}
@@ -105,6 +167,48 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+//ThrowStatement raises its value as an error
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+//TokenLiteral ...
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+//DeferStatement registers a call to run when the enclosing function returns
+type DeferStatement struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode() {}
+
+//TokenLiteral ...
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+
+func (ds *DeferStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ds.TokenLiteral() + " ")
+	if ds.Call != nil {
+		out.WriteString(ds.Call.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 //ExpressionStatement ...
 type ExpressionStatement struct {
 	Token      token.Token
@@ -247,11 +351,81 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+//TryExpression is try { } catch (e) { } finally { }. Either Catch or Finally may be nil.
+type TryExpression struct {
+	Token      token.Token // The 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+//TokenLiteral ...
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+//String ...
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch(")
+		out.WriteString(te.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
+//TypeNames are the types an annotation can name. any accepts every value and
+//fn both functions and builtins.
+var TypeNames = []string{"int", "bool", "string", "array", "hash", "fn", "range", "null", "any"}
+
+//TypeAnnotation is the type given to a parameter, a let or the result of a
+//function, e.g. int in let x: int = 1
+type TypeAnnotation struct {
+	Token token.Token // the type name
+	Name  string
+}
+
+//TokenLiteral ...
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
+
 //FunctionLiteral ...
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	ParamTypes []*TypeAnnotation // nil if no parameter is annotated, else one per parameter
+	ReturnType *TypeAnnotation
 	Body       *BlockStatement
+	Doc        string // text of the /// comment above the declaration
+	Name       string // name of the let or const declaring the function, empty if none
+
+	//set by the resolver: the number of slots of the function's scope, the
+	//slot of self if the function uses it, and where the self of the
+	//enclosing function is found from the scope the function is defined in
+	Slots     int
+	Self      *Binding
+	OuterSelf *Binding
+}
+
+//ParamType returns the annotation of the i-th parameter, or nil
+func (fl *FunctionLiteral) ParamType(i int) *TypeAnnotation {
+	if i < len(fl.ParamTypes) {
+		return fl.ParamTypes[i]
+	}
+	return nil
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -264,19 +438,56 @@ func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		if typ := fl.ParamType(i); typ != nil {
+			params = append(params, p.String()+": "+typ.String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+//MacroLiteral ...
+type MacroLiteral struct {
+	Token      token.Token // The 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+
+//TokenLiteral ...
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+
+//String ...
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
 //CallExpression ...
 type CallExpression struct {
 	Token     token.Token
@@ -318,6 +529,20 @@ func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+//ImportExpression loads the module at Path
+type ImportExpression struct {
+	Token token.Token // The 'import' token
+	Path  *StringLiteral
+}
+
+func (ie *ImportExpression) expressionNode() {}
+
+//TokenLiteral ...
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path.Value + "\""
+}
+
 //ArrayLiteral ...
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
@@ -366,9 +591,16 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+//HashPair is a key: value entry of a HashLiteral
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+//HashLiteral keeps its pairs in source order
 type HashLiteral struct {
 	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Pairs []HashPair
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -377,8 +609,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -387,3 +619,55 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+//MemberExpression is obj.property, sugar for indexing a hash by a string key
+type MemberExpression struct {
+	Token    token.Token // The . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+//TokenLiteral ...
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+//RangeExpression is start..end, start..<end or either with a trailing ..step
+type RangeExpression struct {
+	Token     token.Token // The .. or ..< token
+	Start     Expression
+	End       Expression
+	Step      Expression // nil when no step is given
+	Exclusive bool
+}
+
+func (re *RangeExpression) expressionNode() {}
+
+//TokenLiteral ...
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(re.Start.String())
+	out.WriteString(re.TokenLiteral())
+	out.WriteString(re.End.String())
+	if re.Step != nil {
+		out.WriteString("..")
+		out.WriteString(re.Step.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
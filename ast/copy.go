@@ -0,0 +1,248 @@
+package ast
+
+//Copy returns a deep copy of node, which can be modified without changing node
+func Copy(node Node) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		c := *node
+		c.Statements = copyStatements(node.Statements)
+		return &c
+
+	case *BadStatement:
+		c := *node
+		return &c
+
+	case *BadExpression:
+		c := *node
+		return &c
+
+	case *LetStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Type = copyType(node.Type)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *ConstStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *Identifier:
+		return copyIdentifier(node)
+
+	case *ReturnStatement:
+		c := *node
+		c.ReturnValue = copyExpression(node.ReturnValue)
+		return &c
+
+	case *ThrowStatement:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+
+	case *DeferStatement:
+		c := *node
+		if node.Call != nil {
+			c.Call = Copy(node.Call).(*CallExpression)
+		}
+		return &c
+
+	case *ExpressionStatement:
+		c := *node
+		c.Expression = copyExpression(node.Expression)
+		return &c
+
+	case *IntegerLiteral:
+		c := *node
+		return &c
+
+	case *PrefixExpression:
+		c := *node
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *InfixExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Right = copyExpression(node.Right)
+		return &c
+
+	case *Boolean:
+		c := *node
+		return &c
+
+	case *BlockStatement:
+		return copyBlock(node)
+
+	case *IfExpression:
+		c := *node
+		c.Condition = copyExpression(node.Condition)
+		c.Consequence = copyBlock(node.Consequence)
+		c.Alternative = copyBlock(node.Alternative)
+		return &c
+
+	case *TryExpression:
+		c := *node
+		c.Block = copyBlock(node.Block)
+		c.CatchParam = copyIdentifier(node.CatchParam)
+		c.Catch = copyBlock(node.Catch)
+		c.Finally = copyBlock(node.Finally)
+		return &c
+
+	case *FunctionLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		if node.ParamTypes != nil {
+			c.ParamTypes = make([]*TypeAnnotation, len(node.ParamTypes))
+			for i, t := range node.ParamTypes {
+				c.ParamTypes[i] = copyType(t)
+			}
+		}
+		c.ReturnType = copyType(node.ReturnType)
+		c.Body = copyBlock(node.Body)
+		c.Self = copyBinding(node.Self)
+		c.OuterSelf = copyBinding(node.OuterSelf)
+		return &c
+
+	case *MacroLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		c.Body = copyBlock(node.Body)
+		return &c
+
+	case *CallExpression:
+		c := *node
+		c.Function = copyExpression(node.Function)
+		c.Arguments = copyExpressions(node.Arguments)
+		return &c
+
+	case *StringLiteral:
+		c := *node
+		return &c
+
+	case *ImportExpression:
+		c := *node
+		if node.Path != nil {
+			path := *node.Path
+			c.Path = &path
+		}
+		return &c
+
+	case *ArrayLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+
+	case *IndexExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Index = copyExpression(node.Index)
+		return &c
+
+	case *HashLiteral:
+		c := *node
+		if node.Pairs != nil {
+			c.Pairs = make([]HashPair, len(node.Pairs))
+			for i, pair := range node.Pairs {
+				c.Pairs[i] = HashPair{Key: copyExpression(pair.Key), Value: copyExpression(pair.Value)}
+			}
+		}
+		return &c
+
+	case *MemberExpression:
+		c := *node
+		c.Object = copyExpression(node.Object)
+		c.Property = copyIdentifier(node.Property)
+		return &c
+
+	case *RangeExpression:
+		c := *node
+		c.Start = copyExpression(node.Start)
+		c.End = copyExpression(node.End)
+		c.Step = copyExpression(node.Step)
+		return &c
+
+	}
+
+	return node
+}
+
+func copyExpression(exp Expression) Expression {
+	if exp == nil {
+		return nil
+	}
+	copied, _ := Copy(exp).(Expression)
+	return copied
+}
+
+func copyExpressions(exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	copied := make([]Expression, len(exps))
+	for i, exp := range exps {
+		copied[i] = copyExpression(exp)
+	}
+	return copied
+}
+
+func copyStatements(stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	copied := make([]Statement, len(stmts))
+	for i, stmt := range stmts {
+		if stmt != nil {
+			copied[i], _ = Copy(stmt).(Statement)
+		}
+	}
+	return copied
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	c := *block
+	c.Statements = copyStatements(block.Statements)
+	return &c
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	c.Binding = copyBinding(ident.Binding)
+	return &c
+}
+
+func copyBinding(binding *Binding) *Binding {
+	if binding == nil {
+		return nil
+	}
+	c := *binding
+	return &c
+}
+
+func copyIdentifiers(idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	copied := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		copied[i] = copyIdentifier(ident)
+	}
+	return copied
+}
+
+func copyType(t *TypeAnnotation) *TypeAnnotation {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
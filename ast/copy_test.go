@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	program := walkTestProgram()
+	copied := Copy(program)
+
+	if !reflect.DeepEqual(copied, program) {
+		t.Fatalf("copy differs. want=%q, got=%q", program.String(), copied.String())
+	}
+
+	original := map[Node]bool{}
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			original[node] = true
+		}
+		return true
+	})
+	Inspect(copied, func(node Node) bool {
+		if original[node] {
+			t.Errorf("copy shares node %T %q", node, node.String())
+		}
+		return true
+	})
+
+	before := program.String()
+	Modify(copied, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok {
+			ident.Value = "renamed"
+		}
+		return node
+	})
+	if program.String() != before {
+		t.Errorf("modifying the copy changed the original. want=%q, got=%q", before, program.String())
+	}
+}
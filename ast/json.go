@@ -0,0 +1,485 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//EncodeJSON encodes a tree as JSON. Every node is an object whose "type" is the
+//name of its Go type, e.g. "LetStatement", with the node's token under "token"
+//and its fields under their lower camel case names. A token is an object with
+//"type", "literal", "line" and "column". Absent optional nodes are null. The
+//Type of a LetStatement is under "typeAnnotation", as "type" is taken.
+func EncodeJSON(node Node) ([]byte, error) {
+	return json.MarshalIndent(encodeNode(node), "", "  ")
+}
+
+//DecodeJSON decodes a program encoded by EncodeJSON
+func DecodeJSON(data []byte) (*Program, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	d := &jsonDecoder{}
+	node := d.node(v)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	program, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("expected a Program, got %T", node)
+	}
+	return program, nil
+}
+
+type jsonObject map[string]interface{}
+
+func encodeToken(tok token.Token) jsonObject {
+	return jsonObject{
+		"type":    string(tok.Type),
+		"literal": tok.Literal,
+		"line":    tok.Line,
+		"column":  tok.Column,
+	}
+}
+
+func encodeNode(node Node) interface{} {
+	switch node := node.(type) {
+	case nil:
+		return nil
+	case *Program:
+		return jsonObject{"type": "Program", "statements": encodeStatements(node.Statements)}
+	case *BadStatement:
+		return jsonObject{"type": "BadStatement", "from": encodeToken(node.From), "to": encodeToken(node.To)}
+	case *BadExpression:
+		return jsonObject{"type": "BadExpression", "from": encodeToken(node.From), "to": encodeToken(node.To)}
+	case *LetStatement:
+		return jsonObject{"type": "LetStatement", "token": encodeToken(node.Token),
+			"name": encodeIdentifier(node.Name), "typeAnnotation": encodeTypeAnnotation(node.Type),
+			"value": encodeNode(node.Value)}
+	case *ConstStatement:
+		return jsonObject{"type": "ConstStatement", "token": encodeToken(node.Token),
+			"name": encodeIdentifier(node.Name), "value": encodeNode(node.Value)}
+	case *Identifier:
+		return encodeIdentifier(node)
+	case *ReturnStatement:
+		return jsonObject{"type": "ReturnStatement", "token": encodeToken(node.Token),
+			"returnValue": encodeNode(node.ReturnValue)}
+	case *ThrowStatement:
+		return jsonObject{"type": "ThrowStatement", "token": encodeToken(node.Token),
+			"value": encodeNode(node.Value)}
+	case *DeferStatement:
+		var call interface{}
+		if node.Call != nil {
+			call = encodeNode(node.Call)
+		}
+		return jsonObject{"type": "DeferStatement", "token": encodeToken(node.Token), "call": call}
+	case *ExpressionStatement:
+		return jsonObject{"type": "ExpressionStatement", "token": encodeToken(node.Token),
+			"expression": encodeNode(node.Expression)}
+	case *IntegerLiteral:
+		return jsonObject{"type": "IntegerLiteral", "token": encodeToken(node.Token), "value": node.Value}
+	case *PrefixExpression:
+		return jsonObject{"type": "PrefixExpression", "token": encodeToken(node.Token),
+			"operator": node.Operator, "right": encodeNode(node.Right)}
+	case *InfixExpression:
+		return jsonObject{"type": "InfixExpression", "token": encodeToken(node.Token),
+			"left": encodeNode(node.Left), "operator": node.Operator, "right": encodeNode(node.Right)}
+	case *Boolean:
+		return jsonObject{"type": "Boolean", "token": encodeToken(node.Token), "value": node.Value}
+	case *BlockStatement:
+		return encodeBlock(node)
+	case *IfExpression:
+		return jsonObject{"type": "IfExpression", "token": encodeToken(node.Token),
+			"condition": encodeNode(node.Condition), "consequence": encodeBlock(node.Consequence),
+			"alternative": encodeBlock(node.Alternative)}
+	case *TryExpression:
+		return jsonObject{"type": "TryExpression", "token": encodeToken(node.Token),
+			"block": encodeBlock(node.Block), "catchParam": encodeIdentifier(node.CatchParam),
+			"catch": encodeBlock(node.Catch), "finally": encodeBlock(node.Finally)}
+	case *FunctionLiteral:
+		var paramTypes interface{}
+		if node.ParamTypes != nil {
+			list := []interface{}{}
+			for _, typ := range node.ParamTypes {
+				list = append(list, encodeTypeAnnotation(typ))
+			}
+			paramTypes = list
+		}
+		return jsonObject{"type": "FunctionLiteral", "token": encodeToken(node.Token),
+			"parameters": encodeIdentifiers(node.Parameters), "paramTypes": paramTypes,
+			"returnType": encodeTypeAnnotation(node.ReturnType), "body": encodeBlock(node.Body), "doc": node.Doc, "name": node.Name}
+	case *MacroLiteral:
+		return jsonObject{"type": "MacroLiteral", "token": encodeToken(node.Token),
+			"parameters": encodeIdentifiers(node.Parameters), "body": encodeBlock(node.Body)}
+	case *CallExpression:
+		return jsonObject{"type": "CallExpression", "token": encodeToken(node.Token),
+			"function": encodeNode(node.Function), "arguments": encodeExpressions(node.Arguments)}
+	case *StringLiteral:
+		return jsonObject{"type": "StringLiteral", "token": encodeToken(node.Token), "value": node.Value}
+	case *ImportExpression:
+		var path interface{}
+		if node.Path != nil {
+			path = encodeNode(node.Path)
+		}
+		return jsonObject{"type": "ImportExpression", "token": encodeToken(node.Token), "path": path}
+	case *ArrayLiteral:
+		return jsonObject{"type": "ArrayLiteral", "token": encodeToken(node.Token),
+			"elements": encodeExpressions(node.Elements)}
+	case *IndexExpression:
+		return jsonObject{"type": "IndexExpression", "token": encodeToken(node.Token),
+			"left": encodeNode(node.Left), "index": encodeNode(node.Index)}
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, pair := range node.Pairs {
+			pairs = append(pairs, jsonObject{"key": encodeNode(pair.Key), "value": encodeNode(pair.Value)})
+		}
+		return jsonObject{"type": "HashLiteral", "token": encodeToken(node.Token), "pairs": pairs}
+	case *MemberExpression:
+		return jsonObject{"type": "MemberExpression", "token": encodeToken(node.Token),
+			"object": encodeNode(node.Object), "property": encodeIdentifier(node.Property)}
+	case *RangeExpression:
+		return jsonObject{"type": "RangeExpression", "token": encodeToken(node.Token),
+			"start": encodeNode(node.Start), "end": encodeNode(node.End), "step": encodeNode(node.Step),
+			"exclusive": node.Exclusive}
+	default:
+		return jsonObject{"type": fmt.Sprintf("%T", node)}
+	}
+}
+
+func encodeIdentifier(ident *Identifier) interface{} {
+	if ident == nil {
+		return nil
+	}
+	return jsonObject{"type": "Identifier", "token": encodeToken(ident.Token), "value": ident.Value}
+}
+
+func encodeTypeAnnotation(typ *TypeAnnotation) interface{} {
+	if typ == nil {
+		return nil
+	}
+	return jsonObject{"type": "TypeAnnotation", "token": encodeToken(typ.Token), "name": typ.Name}
+}
+
+func encodeBlock(block *BlockStatement) interface{} {
+	if block == nil {
+		return nil
+	}
+	return jsonObject{"type": "BlockStatement", "token": encodeToken(block.Token),
+		"statements": encodeStatements(block.Statements)}
+}
+
+func encodeStatements(stmts []Statement) []interface{} {
+	list := []interface{}{}
+	for _, stmt := range stmts {
+		list = append(list, encodeNode(stmt))
+	}
+	return list
+}
+
+func encodeExpressions(exps []Expression) []interface{} {
+	list := []interface{}{}
+	for _, exp := range exps {
+		list = append(list, encodeNode(exp))
+	}
+	return list
+}
+
+func encodeIdentifiers(idents []*Identifier) []interface{} {
+	list := []interface{}{}
+	for _, ident := range idents {
+		list = append(list, encodeIdentifier(ident))
+	}
+	return list
+}
+
+//jsonDecoder keeps the first error it runs into. Once it has failed node
+//returns nil, so decoding stops without checking for errors at every step.
+type jsonDecoder struct {
+	err error
+}
+
+func (d *jsonDecoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (d *jsonDecoder) object(v interface{}) jsonObject {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		d.fail("expected an object, got %T", v)
+		return nil
+	}
+	return obj
+}
+
+func (d *jsonDecoder) node(v interface{}) Node {
+	if v == nil || d.err != nil {
+		return nil
+	}
+
+	obj := d.object(v)
+	if obj == nil {
+		return nil
+	}
+
+	typ := d.str(obj, "type")
+	switch typ {
+	case "Program":
+		return &Program{Statements: d.statements(obj, "statements")}
+	case "BadStatement":
+		return &BadStatement{From: d.token(obj, "from"), To: d.token(obj, "to")}
+	case "BadExpression":
+		return &BadExpression{From: d.token(obj, "from"), To: d.token(obj, "to")}
+	case "LetStatement":
+		return &LetStatement{Token: d.token(obj, "token"), Name: d.identifier(obj, "name"),
+			Type: d.typeAnnotation(obj, "typeAnnotation"), Value: d.expression(obj, "value")}
+	case "ConstStatement":
+		return &ConstStatement{Token: d.token(obj, "token"), Name: d.identifier(obj, "name"),
+			Value: d.expression(obj, "value")}
+	case "Identifier":
+		return &Identifier{Token: d.token(obj, "token"), Value: d.str(obj, "value")}
+	case "ReturnStatement":
+		return &ReturnStatement{Token: d.token(obj, "token"), ReturnValue: d.expression(obj, "returnValue")}
+	case "ThrowStatement":
+		return &ThrowStatement{Token: d.token(obj, "token"), Value: d.expression(obj, "value")}
+	case "DeferStatement":
+		stmt := &DeferStatement{Token: d.token(obj, "token")}
+		if call := d.expression(obj, "call"); call != nil {
+			stmt.Call, _ = call.(*CallExpression)
+			if stmt.Call == nil {
+				d.fail("call: expected CallExpression, got %T", call)
+			}
+		}
+		return stmt
+	case "ExpressionStatement":
+		return &ExpressionStatement{Token: d.token(obj, "token"), Expression: d.expression(obj, "expression")}
+	case "IntegerLiteral":
+		return &IntegerLiteral{Token: d.token(obj, "token"), Value: d.integer(obj, "value")}
+	case "PrefixExpression":
+		return &PrefixExpression{Token: d.token(obj, "token"), Operator: d.str(obj, "operator"),
+			Right: d.expression(obj, "right")}
+	case "InfixExpression":
+		return &InfixExpression{Token: d.token(obj, "token"), Left: d.expression(obj, "left"),
+			Operator: d.str(obj, "operator"), Right: d.expression(obj, "right")}
+	case "Boolean":
+		return &Boolean{Token: d.token(obj, "token"), Value: d.boolean(obj, "value")}
+	case "BlockStatement":
+		return &BlockStatement{Token: d.token(obj, "token"), Statements: d.statements(obj, "statements")}
+	case "IfExpression":
+		return &IfExpression{Token: d.token(obj, "token"), Condition: d.expression(obj, "condition"),
+			Consequence: d.block(obj, "consequence"), Alternative: d.block(obj, "alternative")}
+	case "TryExpression":
+		return &TryExpression{Token: d.token(obj, "token"), Block: d.block(obj, "block"),
+			CatchParam: d.identifier(obj, "catchParam"), Catch: d.block(obj, "catch"),
+			Finally: d.block(obj, "finally")}
+	case "FunctionLiteral":
+		return &FunctionLiteral{Token: d.token(obj, "token"), Parameters: d.identifiers(obj, "parameters"),
+			ParamTypes: d.typeAnnotations(obj, "paramTypes"), ReturnType: d.typeAnnotation(obj, "returnType"),
+			Body: d.block(obj, "body"), Doc: d.str(obj, "doc"), Name: d.str(obj, "name")}
+	case "TypeAnnotation":
+		return &TypeAnnotation{Token: d.token(obj, "token"), Name: d.str(obj, "name")}
+	case "MacroLiteral":
+		return &MacroLiteral{Token: d.token(obj, "token"), Parameters: d.identifiers(obj, "parameters"),
+			Body: d.block(obj, "body")}
+	case "CallExpression":
+		return &CallExpression{Token: d.token(obj, "token"), Function: d.expression(obj, "function"),
+			Arguments: d.expressions(obj, "arguments")}
+	case "StringLiteral":
+		return &StringLiteral{Token: d.token(obj, "token"), Value: d.str(obj, "value")}
+	case "ImportExpression":
+		exp := &ImportExpression{Token: d.token(obj, "token")}
+		if path := d.expression(obj, "path"); path != nil {
+			exp.Path, _ = path.(*StringLiteral)
+			if exp.Path == nil {
+				d.fail("path: expected StringLiteral, got %T", path)
+			}
+		}
+		return exp
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: d.token(obj, "token"), Elements: d.expressions(obj, "elements")}
+	case "IndexExpression":
+		return &IndexExpression{Token: d.token(obj, "token"), Left: d.expression(obj, "left"),
+			Index: d.expression(obj, "index")}
+	case "HashLiteral":
+		hash := &HashLiteral{Token: d.token(obj, "token"), Pairs: []HashPair{}}
+		for _, v := range d.list(obj, "pairs") {
+			pair := d.object(v)
+			if pair == nil {
+				break
+			}
+			hash.Pairs = append(hash.Pairs, HashPair{Key: d.expression(pair, "key"), Value: d.expression(pair, "value")})
+		}
+		return hash
+	case "MemberExpression":
+		return &MemberExpression{Token: d.token(obj, "token"), Object: d.expression(obj, "object"),
+			Property: d.identifier(obj, "property")}
+	case "RangeExpression":
+		return &RangeExpression{Token: d.token(obj, "token"), Start: d.expression(obj, "start"),
+			End: d.expression(obj, "end"), Step: d.expression(obj, "step"), Exclusive: d.boolean(obj, "exclusive")}
+	default:
+		d.fail("unknown node type %q", typ)
+		return nil
+	}
+}
+
+func (d *jsonDecoder) token(obj jsonObject, key string) token.Token {
+	tok := d.object(obj[key])
+	if tok == nil {
+		return token.Token{}
+	}
+	return token.Token{
+		Type:    token.TokenType(d.str(tok, "type")),
+		Literal: d.str(tok, "literal"),
+		Line:    int(d.integer(tok, "line")),
+		Column:  int(d.integer(tok, "column")),
+	}
+}
+
+func (d *jsonDecoder) str(obj jsonObject, key string) string {
+	s, ok := obj[key].(string)
+	if !ok && obj[key] != nil {
+		d.fail("%s: expected a string, got %T", key, obj[key])
+	}
+	return s
+}
+
+func (d *jsonDecoder) integer(obj jsonObject, key string) int64 {
+	n, ok := obj[key].(json.Number)
+	if !ok {
+		d.fail("%s: expected a number, got %T", key, obj[key])
+		return 0
+	}
+	i, err := n.Int64()
+	if err != nil {
+		d.fail("%s: %s", key, err)
+	}
+	return i
+}
+
+func (d *jsonDecoder) boolean(obj jsonObject, key string) bool {
+	b, ok := obj[key].(bool)
+	if !ok {
+		d.fail("%s: expected a boolean, got %T", key, obj[key])
+	}
+	return b
+}
+
+func (d *jsonDecoder) list(obj jsonObject, key string) []interface{} {
+	l, ok := obj[key].([]interface{})
+	if !ok {
+		d.fail("%s: expected a list, got %T", key, obj[key])
+	}
+	return l
+}
+
+func (d *jsonDecoder) expression(obj jsonObject, key string) Expression {
+	node := d.node(obj[key])
+	if node == nil {
+		return nil
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		d.fail("%s: expected an expression, got %T", key, node)
+	}
+	return exp
+}
+
+func (d *jsonDecoder) statements(obj jsonObject, key string) []Statement {
+	stmts := []Statement{}
+	for _, v := range d.list(obj, key) {
+		node := d.node(v)
+		stmt, ok := node.(Statement)
+		if !ok {
+			d.fail("%s: expected a statement, got %T", key, node)
+			break
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+func (d *jsonDecoder) expressions(obj jsonObject, key string) []Expression {
+	exps := []Expression{}
+	for _, v := range d.list(obj, key) {
+		node := d.node(v)
+		exp, ok := node.(Expression)
+		if !ok {
+			d.fail("%s: expected an expression, got %T", key, node)
+			break
+		}
+		exps = append(exps, exp)
+	}
+	return exps
+}
+
+func (d *jsonDecoder) identifier(obj jsonObject, key string) *Identifier {
+	node := d.node(obj[key])
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		d.fail("%s: expected an Identifier, got %T", key, node)
+	}
+	return ident
+}
+
+func (d *jsonDecoder) identifiers(obj jsonObject, key string) []*Identifier {
+	idents := []*Identifier{}
+	for _, v := range d.list(obj, key) {
+		ident, ok := d.node(v).(*Identifier)
+		if !ok {
+			d.fail("%s: expected an Identifier", key)
+			break
+		}
+		idents = append(idents, ident)
+	}
+	return idents
+}
+
+func (d *jsonDecoder) typeAnnotation(obj jsonObject, key string) *TypeAnnotation {
+	node := d.node(obj[key])
+	if node == nil {
+		return nil
+	}
+	typ, ok := node.(*TypeAnnotation)
+	if !ok {
+		d.fail("%s: expected a TypeAnnotation, got %T", key, node)
+	}
+	return typ
+}
+
+//typeAnnotations decodes a list of optional annotations, which is nil when null
+func (d *jsonDecoder) typeAnnotations(obj jsonObject, key string) []*TypeAnnotation {
+	if obj[key] == nil {
+		return nil
+	}
+
+	types := []*TypeAnnotation{}
+	for _, v := range d.list(obj, key) {
+		types = append(types, d.typeAnnotation(jsonObject{key: v}, key))
+	}
+	return types
+}
+
+func (d *jsonDecoder) block(obj jsonObject, key string) *BlockStatement {
+	node := d.node(obj[key])
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.fail("%s: expected a BlockStatement, got %T", key, node)
+	}
+	return block
+}
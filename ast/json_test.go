@@ -0,0 +1,127 @@
+package ast_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `/// adds
+let add = fn(a, b) { a + b };
+const big = 9223372036854775807;
+let h = {"x": [1, true, !false], 2: -3};
+let r = 1..10..2;
+let s = 0..<len(h);
+let m = h.x;
+if (add(1, 2) > 2) { return h["x"][0]; } else { throw "no"; }
+let f = fn() { defer print("bye"); try { 1 / 0 } catch (e) { e.message } finally { 2 } };
+let lib = import "std/io";
+let unless = macro(c, body) { quote(if (!(unquote(c))) { unquote(body) }) };
+let typed: fn = fn(name: string, n) -> array { [name, n] };
+`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %s", err)
+	}
+
+	decoded, err := ast.DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("DecodeJSON failed: %s", err)
+	}
+
+	if decoded.String() != program.String() {
+		t.Errorf("String() differs after round trip.\nwant=%q\ngot=%q", program.String(), decoded.String())
+	}
+
+	if !reflect.DeepEqual(decoded, program) {
+		t.Errorf("decoded tree differs from the parsed one")
+	}
+
+	again, err := ast.EncodeJSON(decoded)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %s", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("encoding is not stable")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	l := lexer.New("x")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %s", err)
+	}
+
+	expected := `{
+  "statements": [
+    {
+      "expression": {
+        "token": {
+          "column": 1,
+          "line": 1,
+          "literal": "x",
+          "type": "IDENT"
+        },
+        "type": "Identifier",
+        "value": "x"
+      },
+      "token": {
+        "column": 1,
+        "line": 1,
+        "literal": "x",
+        "type": "IDENT"
+      },
+      "type": "ExpressionStatement"
+    }
+  ],
+  "type": "Program"
+}`
+
+	if string(data) != expected {
+		t.Errorf("wrong encoding.\nwant=%s\ngot=%s", expected, data)
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "expected an object, got []interface {}"},
+		{`{"type": "Nope"}`, `unknown node type "Nope"`},
+		{`{"type": "Identifier", "value": "x", "token": {"type": "IDENT", "literal": "x", "line": 1, "column": 1}}`,
+			"expected a Program, got *ast.Identifier"},
+		{`{"type": "Program", "statements": [{"type": "Boolean", "value": true, "token": {"line": 1, "column": 1}}]}`,
+			"statements: expected a statement, got *ast.Boolean"},
+		{`{"type": "Program", "statements": 1}`, "statements: expected a list, got json.Number"},
+		{`{"type": "Program"`, "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.DecodeJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package ast
+
+//ModifierFunc is called by Modify on every node and returns its replacement
+type ModifierFunc func(Node) Node
+
+//Modify rewrites the tree bottom-up: the children of a node are modified
+//before the node itself is passed to modifier.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+
+	case *ExpressionStatement:
+		node.Expression, _ = Modify(node.Expression, modifier).(Expression)
+
+	case *InfixExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		node.Right, _ = Modify(node.Right, modifier).(Expression)
+
+	case *PrefixExpression:
+		node.Right, _ = Modify(node.Right, modifier).(Expression)
+
+	case *IndexExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		node.Index, _ = Modify(node.Index, modifier).(Expression)
+
+	case *MemberExpression:
+		node.Object, _ = Modify(node.Object, modifier).(Expression)
+		node.Property = modifyIdentifier(node.Property, modifier)
+
+	case *RangeExpression:
+		node.Start, _ = Modify(node.Start, modifier).(Expression)
+		node.End, _ = Modify(node.End, modifier).(Expression)
+		if node.Step != nil {
+			node.Step, _ = Modify(node.Step, modifier).(Expression)
+		}
+
+	case *IfExpression:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+
+	case *BlockStatement:
+		for i := range node.Statements {
+			node.Statements[i], _ = Modify(node.Statements[i], modifier).(Statement)
+		}
+
+	case *ReturnStatement:
+		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+
+	case *ThrowStatement:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *DeferStatement:
+		node.Call, _ = Modify(node.Call, modifier).(*CallExpression)
+
+	case *TryExpression:
+		node.Block, _ = Modify(node.Block, modifier).(*BlockStatement)
+		if node.CatchParam != nil {
+			node.CatchParam = modifyIdentifier(node.CatchParam, modifier)
+		}
+		if node.Catch != nil {
+			node.Catch, _ = Modify(node.Catch, modifier).(*BlockStatement)
+		}
+		if node.Finally != nil {
+			node.Finally, _ = Modify(node.Finally, modifier).(*BlockStatement)
+		}
+
+	case *LetStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Type = modifyType(node.Type, modifier)
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *ConstStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *FunctionLiteral:
+		for i := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(node.Parameters[i], modifier)
+		}
+		for i := range node.ParamTypes {
+			node.ParamTypes[i] = modifyType(node.ParamTypes[i], modifier)
+		}
+		node.ReturnType = modifyType(node.ReturnType, modifier)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+	case *MacroLiteral:
+		for i := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(node.Parameters[i], modifier)
+		}
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+	case *CallExpression:
+		node.Function, _ = Modify(node.Function, modifier).(Expression)
+		for i := range node.Arguments {
+			node.Arguments[i], _ = Modify(node.Arguments[i], modifier).(Expression)
+		}
+
+	case *ImportExpression:
+		if path, ok := Modify(node.Path, modifier).(*StringLiteral); ok {
+			node.Path = path
+		}
+
+	case *ArrayLiteral:
+		for i := range node.Elements {
+			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expression)
+		}
+
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key, _ = Modify(pair.Key, modifier).(Expression)
+			node.Pairs[i].Value, _ = Modify(pair.Value, modifier).(Expression)
+		}
+
+	}
+
+	return modifier(node)
+}
+
+//modifyIdentifier modifies an identifier in a binding position, such as a let
+//name or a parameter. Only an *Identifier may replace it, anything else is ignored.
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
+
+//modifyType modifies a type annotation, which may be nil. Only a
+//*TypeAnnotation may replace it, anything else is ignored.
+func modifyType(typ *TypeAnnotation, modifier ModifierFunc) *TypeAnnotation {
+	if typ == nil {
+		return nil
+	}
+	if modified, ok := Modify(typ, modifier).(*TypeAnnotation); ok {
+		return modified
+	}
+	return typ
+}
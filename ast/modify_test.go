@@ -0,0 +1,306 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&MemberExpression{Object: one(), Property: &Identifier{Value: "x"}},
+			&MemberExpression{Object: two(), Property: &Identifier{Value: "x"}},
+		},
+		{
+			&RangeExpression{Start: one(), End: one(), Step: one()},
+			&RangeExpression{Start: two(), End: two(), Step: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&ThrowStatement{Value: one()},
+			&ThrowStatement{Value: two()},
+		},
+		{
+			&DeferStatement{Call: &CallExpression{Function: one(), Arguments: []Expression{one()}}},
+			&DeferStatement{Call: &CallExpression{Function: two(), Arguments: []Expression{two()}}},
+		},
+		{
+			&TryExpression{
+				Block: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Finally: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&TryExpression{
+				Block: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Finally: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&ConstStatement{Value: one()},
+			&ConstStatement{Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		equal := reflect.DeepEqual(modified, tt.expected)
+		if !equal {
+			t.Errorf("not equal. got=%#v, want=%#v",
+				modified, tt.expected)
+		}
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: []HashPair{
+			{Key: one(), Value: one()},
+			{Key: one(), Value: one()},
+		},
+	}
+
+	Modify(hashLiteral, turnOneIntoTwo)
+
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := pair.Value.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
+
+func TestModifyIdentifiers(t *testing.T) {
+	ident := func(name string) *Identifier { return &Identifier{Value: name} }
+	block := func(exps ...Expression) *BlockStatement {
+		stmts := []Statement{}
+		for _, exp := range exps {
+			stmts = append(stmts, &ExpressionStatement{Expression: exp})
+		}
+		return &BlockStatement{Statements: stmts}
+	}
+
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{Name: ident("x"), Value: ident("x")},
+			&ConstStatement{Name: ident("x"), Value: &MemberExpression{Object: ident("x"), Property: ident("x")}},
+			&ExpressionStatement{Expression: &FunctionLiteral{
+				Parameters: []*Identifier{ident("x")},
+				Body:       block(ident("x")),
+			}},
+			&ExpressionStatement{Expression: &TryExpression{
+				Block:      block(ident("x")),
+				CatchParam: ident("x"),
+				Catch:      block(ident("x")),
+			}},
+		},
+	}
+
+	renamed := 0
+	Modify(program, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok && ident.Value == "x" {
+			renamed++
+			return &Identifier{Value: "y"}
+		}
+		return node
+	})
+
+	if renamed != 10 {
+		t.Errorf("wrong number of identifiers renamed. want=10, got=%d", renamed)
+	}
+
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok && ident.Value != "y" {
+			t.Errorf("identifier not renamed. got=%q", ident.Value)
+		}
+		return true
+	})
+
+	Modify(program, func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &IntegerLiteral{Value: 1}
+		}
+		return node
+	})
+
+	let := program.Statements[0].(*LetStatement)
+	if let.Name == nil || let.Name.Value != "y" {
+		t.Errorf("let name replaced by a non identifier. got=%#v", let.Name)
+	}
+	if _, ok := let.Value.(*IntegerLiteral); !ok {
+		t.Errorf("let value not replaced. got=%T", let.Value)
+	}
+}
+
+func TestModifyTypeAnnotations(t *testing.T) {
+	typ := func(name string) *TypeAnnotation { return &TypeAnnotation{Name: name} }
+
+	fn := &FunctionLiteral{
+		Parameters: []*Identifier{{Value: "a"}, {Value: "b"}},
+		ParamTypes: []*TypeAnnotation{typ("int"), nil},
+		ReturnType: typ("int"),
+		Body:       &BlockStatement{},
+	}
+	let := &LetStatement{Name: &Identifier{Value: "f"}, Type: typ("int"), Value: fn}
+
+	Modify(&Program{Statements: []Statement{let}}, func(node Node) Node {
+		if typ, ok := node.(*TypeAnnotation); ok && typ.Name == "int" {
+			return &TypeAnnotation{Name: "string"}
+		}
+		return node
+	})
+
+	if let.Type.Name != "string" {
+		t.Errorf("let type not modified. got=%q", let.Type.Name)
+	}
+	if fn.ParamTypes[0].Name != "string" {
+		t.Errorf("parameter type not modified. got=%q", fn.ParamTypes[0].Name)
+	}
+	if fn.ParamTypes[1] != nil {
+		t.Errorf("missing parameter type replaced. got=%#v", fn.ParamTypes[1])
+	}
+	if fn.ReturnType.Name != "string" {
+		t.Errorf("return type not modified. got=%q", fn.ReturnType.Name)
+	}
+
+	Modify(let, func(node Node) Node {
+		if _, ok := node.(*TypeAnnotation); ok {
+			return &Identifier{Value: "x"}
+		}
+		return node
+	})
+
+	if let.Type == nil || fn.ReturnType == nil || fn.ParamTypes[0] == nil {
+		t.Errorf("type annotation replaced by a non type annotation")
+	}
+}
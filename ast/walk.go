@@ -0,0 +1,147 @@
+package ast
+
+//Visitor is called by Walk for every node. If Visit returns a non-nil Visitor w,
+//Walk visits each child of node with w and then calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+//Walk traverses the tree depth-first, in source order
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch node := node.(type) {
+
+	case *Program:
+		for _, statement := range node.Statements {
+			Walk(v, statement)
+		}
+
+	case *LetStatement:
+		Walk(v, node.Name)
+		if node.Type != nil {
+			Walk(v, node.Type)
+		}
+		Walk(v, node.Value)
+
+	case *ConstStatement:
+		Walk(v, node.Name)
+		Walk(v, node.Value)
+
+	case *ReturnStatement:
+		Walk(v, node.ReturnValue)
+
+	case *ThrowStatement:
+		Walk(v, node.Value)
+
+	case *DeferStatement:
+		Walk(v, node.Call)
+
+	case *ExpressionStatement:
+		Walk(v, node.Expression)
+
+	case *BlockStatement:
+		for _, statement := range node.Statements {
+			Walk(v, statement)
+		}
+
+	case *PrefixExpression:
+		Walk(v, node.Right)
+
+	case *InfixExpression:
+		Walk(v, node.Left)
+		Walk(v, node.Right)
+
+	case *IfExpression:
+		Walk(v, node.Condition)
+		Walk(v, node.Consequence)
+		if node.Alternative != nil {
+			Walk(v, node.Alternative)
+		}
+
+	case *TryExpression:
+		Walk(v, node.Block)
+		if node.CatchParam != nil {
+			Walk(v, node.CatchParam)
+		}
+		if node.Catch != nil {
+			Walk(v, node.Catch)
+		}
+		if node.Finally != nil {
+			Walk(v, node.Finally)
+		}
+
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			Walk(v, param)
+			if typ := node.ParamType(i); typ != nil {
+				Walk(v, typ)
+			}
+		}
+		if node.ReturnType != nil {
+			Walk(v, node.ReturnType)
+		}
+		Walk(v, node.Body)
+
+	case *MacroLiteral:
+		for _, param := range node.Parameters {
+			Walk(v, param)
+		}
+		Walk(v, node.Body)
+
+	case *CallExpression:
+		Walk(v, node.Function)
+		for _, arg := range node.Arguments {
+			Walk(v, arg)
+		}
+
+	case *ImportExpression:
+		Walk(v, node.Path)
+
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			Walk(v, el)
+		}
+
+	case *IndexExpression:
+		Walk(v, node.Left)
+		Walk(v, node.Index)
+
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			Walk(v, pair.Key)
+			Walk(v, pair.Value)
+		}
+
+	case *MemberExpression:
+		Walk(v, node.Object)
+		Walk(v, node.Property)
+
+	case *RangeExpression:
+		Walk(v, node.Start)
+		Walk(v, node.End)
+		if node.Step != nil {
+			Walk(v, node.Step)
+		}
+
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+//Inspect walks the tree calling f for every node. The children of a node are
+//skipped when f returns false. After the children f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
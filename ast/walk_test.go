@@ -0,0 +1,111 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+type identCollector struct {
+	names  []string
+	visits int
+	exits  int
+}
+
+func (c *identCollector) Visit(node Node) Visitor {
+	if node == nil {
+		c.exits++
+		return nil
+	}
+	c.visits++
+	if ident, ok := node.(*Identifier); ok {
+		c.names = append(c.names, ident.Value)
+	}
+	return c
+}
+
+func walkTestProgram() *Program {
+	ident := func(name string) *Identifier { return &Identifier{Value: name} }
+	block := func(exp Expression) *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: exp}}}
+	}
+
+	return &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: ident("a"),
+				Value: &HashLiteral{Pairs: []HashPair{
+					{Key: ident("k"), Value: ident("v")},
+				}},
+			},
+			&ExpressionStatement{Expression: &IfExpression{
+				Condition:   ident("c"),
+				Consequence: block(ident("t")),
+				Alternative: block(ident("f")),
+			}},
+			&ExpressionStatement{Expression: &FunctionLiteral{
+				Parameters: []*Identifier{ident("p")},
+				Body: block(&CallExpression{
+					Function:  &MemberExpression{Object: ident("o"), Property: ident("m")},
+					Arguments: []Expression{&RangeExpression{Start: ident("s"), End: ident("e")}},
+				}),
+			}},
+			&ExpressionStatement{Expression: &TryExpression{
+				Block:      block(ident("b")),
+				CatchParam: ident("err"),
+				Catch:      block(ident("h")),
+			}},
+		},
+	}
+}
+
+func TestWalk(t *testing.T) {
+	c := &identCollector{}
+	Walk(c, walkTestProgram())
+
+	expected := []string{"a", "k", "v", "c", "t", "f", "p", "o", "m", "s", "e", "b", "err", "h"}
+	if !reflect.DeepEqual(c.names, expected) {
+		t.Errorf("wrong visiting order. want=%v, got=%v", expected, c.names)
+	}
+
+	if c.exits != c.visits {
+		t.Errorf("nil not visited after every node. visits=%d, nil visits=%d", c.visits, c.exits)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	names := []string{}
+	depth, maxDepth := 0, 0
+
+	Inspect(walkTestProgram(), func(node Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		switch node := node.(type) {
+		case *FunctionLiteral, *TryExpression:
+			depth--
+			return false
+		case *Identifier:
+			names = append(names, node.Value)
+		}
+		return true
+	})
+
+	expected := []string{"a", "k", "v", "c", "t", "f"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong identifiers inspected. want=%v, got=%v", expected, names)
+	}
+
+	if depth != 0 {
+		t.Errorf("nil was not passed after every node's children. depth=%d", depth)
+	}
+
+	if maxDepth != 6 {
+		t.Errorf("wrong max depth. want=6, got=%d", maxDepth)
+	}
+}
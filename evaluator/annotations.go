@@ -0,0 +1,32 @@
+package evaluator
+
+import (
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/object"
+)
+
+//annotationTypes are the object types accepted by each of ast.TypeNames, but any
+var annotationTypes = map[string][]object.ObjectType{
+	"int":    {object.INTEGER_OBJ},
+	"bool":   {object.BOOLEAN_OBJ},
+	"string": {object.STRING_OBJ},
+	"array":  {object.ARRAY_OBJ},
+	"hash":   {object.HASH_OBJ},
+	"fn":     {object.FUNCTION_OBJ, object.BUILTIN_OBJ},
+	"range":  {object.RANGE_OBJ},
+	"null":   {object.NULL_OBJ},
+}
+
+//hasType reports whether obj is a value of the annotated type
+func hasType(obj object.Object, typ *ast.TypeAnnotation) bool {
+	if typ.Name == "any" {
+		return true
+	}
+
+	for _, t := range annotationTypes[typ.Name] {
+		if obj.Type() == t {
+			return true
+		}
+	}
+	return false
+}
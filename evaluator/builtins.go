@@ -2,15 +2,19 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/literallystan/go-terpreter/object"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
+		Doc:   "len(x) returns the number of elements in an array or range, or of bytes in a string",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return wrongArguments(len(args), 1)
 			}
 
 			switch arg := args[0].(type) {
@@ -18,16 +22,22 @@ var builtins = map[string]*object.Builtin{
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"first": &object.Builtin{
+		Doc:   "first(arr) returns the first element of an array or range, or null if it is empty",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
+				return wrongArguments(len(args), 1)
+			}
+			if rng, ok := args[0].(*object.Range); ok {
+				return rangeElement(rng, 0)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
@@ -42,10 +52,18 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": &object.Builtin{
+		Doc:   "last(arr) returns the last element of an array or range, or null if it is empty",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
+				return wrongArguments(len(args), 1)
+			}
+			if rng, ok := args[0].(*object.Range); ok {
+				value, ok := rng.Last()
+				if !ok {
+					return NULL
+				}
+				return &object.Integer{Value: value}
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
@@ -64,8 +82,7 @@ var builtins = map[string]*object.Builtin{
 		"tail": &object.Builtin{
 			Fn: func(args ...object.Object) object.Object {
 				if len(args) != 1 {
-					return newError("wrong number of arguments. got=%d, want=1",
-						len(args))
+					return wrongArguments(len(args), 1)
 				}
 				if args[0].Type() != object.ARRAY_OBJ {
 					return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
@@ -83,8 +100,7 @@ var builtins = map[string]*object.Builtin{
 		"push": &object.Builtin{
 			Fn: func(args ...object.Object) object.Object {
 				if len(args) != 2 {
-					return newError("wrong number of arguments. got=%d, want=2",
-						len(args))
+					return wrongArguments(len(args), 2)
 				}
 				if args[0].Type() != object.ARRAY_OBJ {
 					return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
@@ -103,10 +119,26 @@ var builtins = map[string]*object.Builtin{
 	//BOOK EXAMPLES i.e IMMUTABLE ARRAYS
 
 	"tail": &object.Builtin{
+		Doc:   "tail(arr) returns everything after the first element, or null if it is empty",
+		Arity: 1,
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
+				return wrongArguments(len(args), 1)
+			}
+			if rng, ok := args[0].(*object.Range); ok {
+				switch rng.Len() {
+				case 0:
+					return NULL
+				case 1:
+					// Start + Step may overflow past the only integer
+					return &object.Range{Start: rng.Start, End: rng.Start, Step: rng.Step, Exclusive: true}
+				}
+				return &object.Range{
+					Start:     rng.Start + rng.Step,
+					End:       rng.End,
+					Step:      rng.Step,
+					Exclusive: rng.Exclusive,
+				}
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `rest` must be ARRAY, got %s",
@@ -125,10 +157,11 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": &object.Builtin{
+		Doc:   "push(arr, x) returns a new array with x appended to arr",
+		Arity: 2,
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2",
-					len(args))
+				return wrongArguments(len(args), 2)
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
 				return newError("argument to `push` must be ARRAY, got %s",
@@ -145,7 +178,98 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"contains": &object.Builtin{
+		Doc: "contains(c, x) reports whether x is an element of an array or range, a " +
+			"substring of a string or a key of a hash",
+		Arity: 2,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return wrongArguments(len(args), 2)
+			}
+
+			switch collection := args[0].(type) {
+			case *object.Range:
+				integer, ok := args[1].(*object.Integer)
+				return nativeBoolToBooleanObject(ok && collection.Contains(integer.Value))
+			case *object.Array:
+				for _, el := range collection.Elements {
+					if objectsEqual(el, args[1]) {
+						return TRUE
+					}
+				}
+				return FALSE
+			case *object.String:
+				str, ok := args[1].(*object.String)
+				if !ok {
+					return newError("argument to `contains` must be STRING, got %s",
+						args[1].Type())
+				}
+				return nativeBoolToBooleanObject(strings.Contains(collection.Value, str.Value))
+			case *object.Hash:
+				key, ok := args[1].(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", args[1].Type())
+				}
+				_, ok = collection.Pairs[key.HashKey()]
+				return nativeBoolToBooleanObject(ok)
+			default:
+				return newError("argument to `contains` not supported, got %s",
+					args[0].Type())
+			}
+		},
+	},
+	"array": &object.Builtin{
+		Doc:   "array(r) returns the elements of a range as an array",
+		Arity: 1,
+		Check: func(limits *object.Limits, args ...object.Object) *object.Error {
+			if len(args) == 1 {
+				if r, ok := args[0].(*object.Range); ok {
+					return limits.CheckLength(r.Len())
+				}
+			}
+			return nil
+		},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArguments(len(args), 1)
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Range:
+				arr, errObj := arg.ToArray()
+				if errObj != nil {
+					return errObj
+				}
+				return arr
+			case *object.Array:
+				return arg
+			default:
+				return newError("argument to `array` not supported, got %s",
+					args[0].Type())
+			}
+		},
+	},
+	"doc": &object.Builtin{
+		Doc:   "doc(f) returns the documentation of a function or builtin",
+		Arity: 1,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArguments(len(args), 1)
+			}
+
+			switch fn := args[0].(type) {
+			case *object.Function:
+				return &object.String{Value: fn.Doc}
+			case *object.Builtin:
+				return &object.String{Value: fn.Doc}
+			default:
+				return newError("argument to `doc` must be FUNCTION or BUILTIN, got %s",
+					args[0].Type())
+			}
+		},
+	},
 	"print": &object.Builtin{
+		Doc: "print(args...) prints the value of each argument on its own line",
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
 				fmt.Println(arg.Inspect())
@@ -155,3 +279,21 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 }
+
+//BuiltinNames returns the names of the builtin functions, sorted
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func rangeElement(rng *object.Range, i int64) object.Object {
+	value, ok := rng.At(i)
+	if !ok {
+		return NULL
+	}
+	return &object.Integer{Value: value}
+}
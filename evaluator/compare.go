@@ -0,0 +1,91 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/literallystan/go-terpreter/object"
+)
+
+//objectsEqual reports whether a and b are equal, as == does. Integers, strings
+//and booleans are equal when their values are, arrays when their elements are
+//equal in order, hashes when they map the same keys to equal values, whatever
+//their order, and null equals null. Any other value, such as a function, a
+//builtin, a range or a module, is only equal to itself: two functions are
+//never equal unless they are the same function value.
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value == b.(*object.Integer).Value
+	case *object.String:
+		return a.Value == b.(*object.String).Value
+	case *object.Boolean:
+		return a.Value == b.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	case *object.Array:
+		other := b.(*object.Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !objectsEqual(el, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other := b.(*object.Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+//compareObjects orders a and b for operator, which is < or >, returning a
+//negative number if a comes first, zero if neither does and a positive number
+//otherwise. Integers are ordered by value, strings byte by byte and arrays
+//element by element, an array coming before the longer ones it begins. Other
+//values have no order.
+func compareObjects(operator string, a, b object.Object) (int, *object.Error) {
+	if a.Type() != b.Type() {
+		return 0, newError("type mismatch: %s %s %s", a.Type(), operator, b.Type())
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		other := b.(*object.Integer).Value
+		switch {
+		case a.Value < other:
+			return -1, nil
+		case a.Value > other:
+			return 1, nil
+		}
+		return 0, nil
+	case *object.String:
+		return strings.Compare(a.Value, b.(*object.String).Value), nil
+	case *object.Array:
+		other := b.(*object.Array)
+		for i := 0; i < len(a.Elements) && i < len(other.Elements); i++ {
+			order, errObj := compareObjects(operator, a.Elements[i], other.Elements[i])
+			if errObj != nil || order != 0 {
+				return order, errObj
+			}
+		}
+		return len(a.Elements) - len(other.Elements), nil
+	default:
+		return 0, newError("unknown operator: %s %s %s", a.Type(), operator, b.Type())
+	}
+}
@@ -0,0 +1,32 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/object"
+)
+
+//EvalContext evaluates node like Eval, but returns an error once ctx is done.
+//ctx is checked at every function call and block statement, so a script that
+//never returns is stopped by cancelling ctx or giving it a deadline.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	previous := env.Context()
+	env.SetContext(ctx)
+	defer env.SetContext(previous)
+
+	return Eval(node, env)
+}
+
+//checkContext returns an error if the context of env is done
+func checkContext(env *object.Environment) *object.Error {
+	ctx := env.Context()
+	if ctx == nil {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return newError("evaluation cancelled: %s", err)
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package evaluator
 
 import (
 	"fmt"
+	"runtime/debug"
 
 	"github.com/literallystan/go-terpreter/ast"
 	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/token"
 )
 
 var (
@@ -13,63 +15,128 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
-//Eval evaluates a ast.Node and returns the corresponding Object as defined in the object package
-func Eval(node ast.Node, env *object.Environment) object.Object {
+//Eval evaluates a ast.Node and returns the corresponding Object as defined in the object package.
+//A panic raised while evaluating is recovered and returned as an error.
+func Eval(node ast.Node, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Error{
+				Message:  fmt.Sprintf("internal error: %v", r),
+				Internal: string(debug.Stack()),
+			}
+		}
+	}()
+
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
+	if errObj := step(env); errObj != nil {
+		return errObj
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return eval(node.Expression, env)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node.Token)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Type != nil && !hasType(val, node.Type) {
+			return withPosition(newError("value of %s must be %s, got %s",
+				node.Name.Value, node.Type.Name, val.Type()), node.Token)
+		}
+		if result := bindName(env, node.Name, val); isError(result) {
+			return withPosition(result, node.Token)
+		}
+		return nil
+	case *ast.ConstStatement:
+		val := eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		if result := bindConst(env, node.Name, val); isError(result) {
+			return withPosition(result, node.Token)
+		}
+		return nil
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(checkSize(evalInfixExpression(node.Operator, left, right), env), node.Token)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		val := eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return &object.Error{
+			Message: thrownMessage(val),
+			Thrown:  val,
+			Line:    node.Token.Line,
+			Column:  node.Token.Column,
+		}
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.DeferStatement:
+		return withPosition(evalDeferStatement(node, env), node.Token)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		if node.Binding != nil {
+			if val, ok := env.GetSlot(node.Binding.Depth, node.Binding.Slot); ok {
+				return val
+			}
+		}
+		// not bound yet, or not resolved
+		return withPosition(evalIdentifier(node, env), node.Token)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if isCallTo(node, "quote") {
+			if len(node.Arguments) != 1 {
+				return wrongArguments(len(node.Arguments), 1)
+			}
+			return quote(node.Arguments[0], env)
+		}
+		function := evalCallee(node.Function, env)
 		if isError(function) {
-			return function
+			return withPosition(function, node.Token)
 		}
 		args := evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return withFrame(withPosition(applyFunction(function, args, env), node.Token), function, node.Token)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, ParamTypes: node.ParamTypes, ReturnType: node.ReturnType,
+			Env: env, Body: body, Doc: node.Doc, Name: node.Name, Line: node.Token.Line, Column: node.Token.Column,
+			Slots: node.Slots, Self: node.Self, OuterSelf: node.OuterSelf}
+	case *ast.MacroLiteral:
+		return &object.Macro{Parameters: node.Parameters, Env: env, Body: node.Body}
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	case *ast.StringLiteral:
@@ -79,28 +146,42 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return withPosition(checkSize(&object.Array{Elements: elements}, env), node.Token)
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node.Token)
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return withPosition(checkSize(evalHashLiteral(node, env), env), node.Token)
+	case *ast.BadStatement:
+		return withPosition(newError("cannot evaluate unparsed statement"), node.From)
+	case *ast.BadExpression:
+		return withPosition(newError("cannot evaluate unparsed expression"), node.From)
+	case *ast.ImportExpression:
+		return withPosition(evalImportExpression(node.Path.Value, env), node.Token)
+	case *ast.RangeExpression:
+		return withPosition(evalRangeExpression(node, env), node.Token)
+	case *ast.MemberExpression:
+		obj := eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return withPosition(evalMemberExpression(obj, node.Property.Value), node.Token)
 	}
-	return nil
+	return newError("cannot evaluate %T", node)
 }
 
 func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -157,12 +238,14 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(objectsEqual(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!objectsEqual(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
+	case operator == "<" || operator == ">":
+		return evalOrderExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	default:
@@ -171,6 +254,18 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+func evalOrderExpression(operator string, left, right object.Object) object.Object {
+	order, errObj := compareObjects(operator, left, right)
+	if errObj != nil {
+		return errObj
+	}
+
+	if operator == "<" {
+		return nativeBoolToBooleanObject(order < 0)
+	}
+	return nativeBoolToBooleanObject(order > 0)
+}
+
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
@@ -183,6 +278,9 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -210,10 +308,14 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	if errObj := checkContext(env); errObj != nil {
+		return errObj
+	}
+
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -225,6 +327,74 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := eval(te.Block, env)
+
+	// an exceeded limit cannot be caught, and finally runs but cannot replace it
+	limited := isLimitError(result)
+	if errObj, ok := result.(*object.Error); ok && !limited && te.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		bindName(catchEnv, te.CatchParam, errorToHash(errObj))
+		result = eval(te.Catch, catchEnv)
+		limited = isLimitError(result)
+	}
+
+	if te.Finally != nil {
+		finally := eval(te.Finally, env)
+		if finally != nil && !limited {
+			rt := finally.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return finally
+			}
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+//isLimitError reports whether obj is the error of an exceeded limit
+func isLimitError(obj object.Object) bool {
+	errObj, ok := obj.(*object.Error)
+	return ok && errObj.Limit != ""
+}
+
+//errorToHash converts an error into the value bound to a catch variable
+func errorToHash(errObj *object.Error) *object.Hash {
+	errType := object.ObjectType(object.ERROR_OBJ)
+	value := object.Object(NULL)
+	if errObj.Thrown != nil {
+		errType = errObj.Thrown.Type()
+		value = errObj.Thrown
+	}
+
+	position := object.NewHash()
+	setStringKey(position, "line", &object.Integer{Value: int64(errObj.Line)})
+	setStringKey(position, "column", &object.Integer{Value: int64(errObj.Column)})
+
+	hash := object.NewHash()
+	setStringKey(hash, "message", &object.String{Value: errObj.Message})
+	setStringKey(hash, "type", &object.String{Value: string(errType)})
+	setStringKey(hash, "position", position)
+	setStringKey(hash, "value", value)
+
+	return hash
+}
+
+func setStringKey(hash *object.Hash, name string, value object.Object) {
+	key := &object.String{Value: name}
+	hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+}
+
+func thrownMessage(val object.Object) string {
+	if str, ok := val.(*object.String); ok {
+		return str.Value
+	}
+	return val.Inspect()
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -238,18 +408,23 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+	condition := eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
+		result = eval(ie.Alternative, env)
+	}
+
+	// a branch without a value, such as an empty one, evaluates to null
+	if result == nil {
 		return NULL
 	}
+	return result
 }
 
 func isTruthy(obj object.Object) bool {
@@ -269,6 +444,62 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+func wrongArguments(got, want int) *object.Error {
+	return newError("wrong number of arguments. got=%d, want=%d", got, want)
+}
+
+//withPosition records tok as the position of obj if it is an error that has none yet
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && errObj.Line == 0 {
+		errObj.Line = tok.Line
+		errObj.Column = tok.Column
+	}
+	return obj
+}
+
+//step counts the evaluation of a node against the limits of env
+func step(env *object.Environment) *object.Error {
+	if limits := env.Limits(); limits != nil {
+		return limits.Step()
+	}
+	return nil
+}
+
+//checkSize returns obj, or an error if it is larger than the limits of env allow
+func checkSize(obj object.Object, env *object.Environment) object.Object {
+	if limits := env.Limits(); limits != nil {
+		if errObj := limits.CheckSize(obj); errObj != nil {
+			return errObj
+		}
+	}
+	return obj
+}
+
+//withFrame records a call of fn at tok on the stack of obj if it is an error
+func withFrame(obj object.Object, fn object.Object, tok token.Token) object.Object {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		return obj
+	}
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return obj
+	}
+
+	errObj.Stack = append(errObj.Stack, newFrame(function, tok))
+	return obj
+}
+
+//newFrame returns the frame of a call of fn at tok
+func newFrame(fn *object.Function, tok token.Token) object.Frame {
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return object.Frame{Function: name, Line: tok.Line, Column: tok.Column,
+		DefLine: fn.Line, DefColumn: fn.Column}
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -283,7 +514,7 @@ func evalExpressions(
 	var result []object.Object
 
 	for _, e := range exps {
-		evaluated := Eval(e, env)
+		evaluated := eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -293,33 +524,172 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+//applyFunction calls fn with args from the environment caller, whose context
+//the call is evaluated in
+func applyFunction(fn object.Object, args []object.Object, caller *object.Environment) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		if errObj := checkContext(caller); errObj != nil {
+			return errObj
+		}
+		limits := caller.Limits()
+		if limits != nil {
+			if errObj := limits.Enter(); errObj != nil {
+				return errObj
+			}
+			defer limits.Leave()
+		}
+
+		return callFunction(fn, args, caller)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		limits := caller.Limits()
+		if limits == nil {
+			return fn.Fn(args...)
+		}
+
+		if fn.Check != nil {
+			if errObj := fn.Check(limits, args...); errObj != nil {
+				return errObj
+			}
+		}
+		return checkSize(fn.Fn(args...), caller)
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+//extendFunctionEnv binds the parameters of fn to args in a new frame, or
+//returns an error for an argument that does not have its parameter's type
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+) (*object.Environment, *object.Error) {
+	if len(args) < len(fn.Parameters) {
+		return nil, wrongArguments(len(args), len(fn.Parameters))
+	}
+
+	env := object.NewFrameEnvironment(fn.Env, fn.Slots)
+	bindSelfSlot(fn, env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(fn.ParamTypes) {
+			typ := fn.ParamTypes[paramIdx]
+			if typ != nil && !hasType(args[paramIdx], typ) {
+				return nil, newError("argument %s must be %s, got %s",
+					param.Value, typ.Name, args[paramIdx].Type())
+			}
+		}
+		bindName(env, param, args[paramIdx])
+	}
+
+	return env, nil
+}
+
+//checkReturnType returns result, null if the body of fn produced no value, or
+//an error if it does not have the return type of fn
+func checkReturnType(fn *object.Function, result object.Object) object.Object {
+	if result == nil {
+		result = NULL
+	}
+	if fn.ReturnType == nil || isError(result) {
+		return result
+	}
+
+	if !hasType(result, fn.ReturnType) {
+		return newError("return value must be %s, got %s", fn.ReturnType.Name, result.Type())
+	}
+	return result
+}
+
+//bindName binds ident to val in the slot the resolver gave it, or by name
+func bindName(env *object.Environment, ident *ast.Identifier, val object.Object) object.Object {
+	if ident.Binding != nil {
+		return env.SetSlot(ident.Binding.Slot, ident.Value, val)
+	}
+	return env.Set(ident.Value, val)
+}
+
+//bindConst binds ident to val as a constant, like bindName
+func bindConst(env *object.Environment, ident *ast.Identifier, val object.Object) object.Object {
+	if ident.Binding != nil {
+		return env.SetConstSlot(ident.Binding.Slot, ident.Value, val)
+	}
+	return env.SetConst(ident.Value, val)
+}
+
+//bindSelfSlot binds self in the frame of a call of fn, before its parameters
+//so that one named self wins. A plain call of a function that uses self gets
+//the self of the function it is nested in. Without a slot for self, as in a
+//function that was not resolved, the receiver is bound by name.
+func bindSelfSlot(fn *object.Function, env *object.Environment) {
+	if fn.Self == nil {
+		if fn.Receiver != nil {
+			env.Set("self", fn.Receiver)
+		}
+		return
 	}
 
-	return env
+	self := fn.Receiver
+	if self == nil && fn.OuterSelf != nil {
+		self, _ = fn.Env.GetSlot(fn.OuterSelf.Depth, fn.OuterSelf.Slot)
+	}
+	if self != nil {
+		env.SetSlot(fn.Self.Slot, "self", self)
+	}
+}
+
+//runDeferredCalls runs the calls deferred on a function frame in LIFO order.
+//An error raised by a deferred call becomes the function's result, or is
+//appended to the error the function is already returning. A limit error
+//stops the remaining deferred calls and keeps its Limit in the result.
+func runDeferredCalls(env *object.Environment, result object.Object) object.Object {
+	deferred := env.Deferred()
+
+	for i := len(deferred) - 1; i >= 0; i-- {
+		call := deferred[i]
+		errObj, ok := applyFunction(call.Fn, call.Args, env).(*object.Error)
+		if !ok {
+			continue
+		}
+
+		if resultErr, ok := result.(*object.Error); ok {
+			combined := *resultErr
+			combined.Message += "; deferred call failed: " + errObj.Message
+			if combined.Limit == "" {
+				combined.Limit = errObj.Limit
+			}
+			result = &combined
+		} else {
+			result = errObj
+		}
+
+		if errObj.Limit != "" {
+			break
+		}
+	}
+
+	return result
+}
+
+func evalDeferStatement(ds *ast.DeferStatement, env *object.Environment) object.Object {
+	function := evalCallee(ds.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := evalExpressions(ds.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if !env.Defer(object.DeferredCall{Fn: function, Args: args}) {
+		return newError("defer outside of function")
+	}
+
+	return nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -334,6 +704,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalRangeIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -353,11 +725,59 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+	rangeObject := rng.(*object.Range)
+	idx := index.(*object.Integer).Value
+
+	value, ok := rangeObject.At(idx)
+	if !ok {
+		return NULL
+	}
+
+	return &object.Integer{Value: value}
+}
+
+func evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+	bounds := []ast.Expression{node.Start, node.End}
+	if node.Step != nil {
+		bounds = append(bounds, node.Step)
+	}
+
+	values := []int64{}
+	for _, b := range bounds {
+		evaluated := eval(b, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+
+		integer, ok := evaluated.(*object.Integer)
+		if !ok {
+			return newError("range bound must be INTEGER, got %s", evaluated.Type())
+		}
+		values = append(values, integer.Value)
+	}
+
+	step := int64(1)
+	if len(values) == 3 {
+		step = values[2]
+	}
+	if step == 0 {
+		return newError("range step must not be zero")
+	}
+
+	return &object.Range{
+		Start:     values[0],
+		End:       values[1],
+		Step:      step,
+		Exclusive: node.Exclusive,
+	}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
@@ -367,16 +787,15 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -394,3 +813,97 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 	return pair.Value
 }
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	if module, ok := obj.(*object.Module); ok {
+		return moduleExport(module, name)
+	}
+
+	if hash, ok := obj.(*object.Hash); ok {
+		key := &object.String{Value: name}
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+	}
+
+	if method, ok := lookupMethod(obj, name); ok {
+		return bindMethod(method, obj)
+	}
+
+	if obj.Type() == object.HASH_OBJ {
+		return NULL
+	}
+
+	return newError("undefined member %s for %s", name, obj.Type())
+}
+
+//evalCallee evaluates the function of a call. For obj.method(...) the result
+//is the method bound to its receiver.
+func evalCallee(node ast.Expression, env *object.Environment) object.Object {
+	member, ok := node.(*ast.MemberExpression)
+	if !ok {
+		return eval(node, env)
+	}
+
+	receiver := eval(member.Object, env)
+	if isError(receiver) {
+		return receiver
+	}
+
+	return resolveMethod(receiver, member.Property.Value)
+}
+
+func resolveMethod(receiver object.Object, name string) object.Object {
+	if module, ok := receiver.(*object.Module); ok {
+		return moduleExport(module, name)
+	}
+
+	if hash, ok := receiver.(*object.Hash); ok {
+		key := &object.String{Value: name}
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			if fn, ok := pair.Value.(*object.Function); ok {
+				return bindSelf(fn, receiver)
+			}
+			return pair.Value
+		}
+	}
+
+	if method, ok := lookupMethod(receiver, name); ok {
+		return bindMethod(method, receiver)
+	}
+
+	return newError("undefined method %s for %s", name, receiver.Type())
+}
+
+func moduleExport(module *object.Module, name string) object.Object {
+	if export, ok := module.Export(name); ok {
+		return export
+	}
+	return newError("module %s has no export %s", module.Name, name)
+}
+
+//bindMethod returns a builtin that calls method with receiver as its first argument
+func bindMethod(method *object.Builtin, receiver object.Object) *object.Builtin {
+	bound := &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if method.Arity != 0 && len(args)+1 != method.Arity {
+				return wrongArguments(len(args), method.Arity-1)
+			}
+			return method.Fn(append([]object.Object{receiver}, args...)...)
+		},
+		Doc: method.Doc,
+	}
+	if method.Check != nil {
+		bound.Check = func(limits *object.Limits, args ...object.Object) *object.Error {
+			return method.Check(limits, append([]object.Object{receiver}, args...)...)
+		}
+	}
+	return bound
+}
+
+//bindSelf returns a copy of fn whose calls bind self to the receiver
+func bindSelf(fn *object.Function, receiver object.Object) *object.Function {
+	bound := *fn
+	bound.Receiver = receiver
+	return &bound
+}
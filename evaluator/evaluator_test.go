@@ -1,11 +1,16 @@
 package evaluator
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/literallystan/go-terpreter/ast"
 	"github.com/literallystan/go-terpreter/lexer"
 	"github.com/literallystan/go-terpreter/object"
 	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/resolver"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -42,6 +47,9 @@ func testEval(input string) object.Object {
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
+	// undefined names are left to fail at runtime
+	resolver.New(BuiltinNames()...).Resolve(program)
+
 	return Eval(program, env)
 }
 
@@ -90,6 +98,81 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"1" == 1`, false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [1, 2, 3]", false},
+		{`[1, 2] == [1, "2"]`, false},
+		{"[] != []", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"let f = fn() { if (false) { 1 } }; f() == f()", true},
+		{"let f = fn() { if (false) { 1 } }; [f()] == [f()]", true},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"len == len", true},
+		{"(1..3) == (1..3)", false},
+		{"contains([[1], [2]], [2])", true},
+		{`contains([{"a": 1}], {"a": 1})`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"a" < "ab"`, true},
+		{`"B" < "a"`, true},
+		{`"a" > "a"`, false},
+		{`"" < "a"`, true},
+		{"[1, 2] < [1, 3]", true},
+		{"[1, 2] > [1, 3]", false},
+		{"[1] < [1, 0]", true},
+		{"[] < [0]", true},
+		{"[2] > [1, 9]", true},
+		{`[["a"], 1] < [["b"], 0]`, true},
+		{"[1, 2] < [1, 2]", false},
+		{`"a" < 1`, "type mismatch: STRING < INTEGER"},
+		{`[1] < ["a"]`, "type mismatch: INTEGER < STRING"},
+		{"[true] < [false]", "unknown operator: BOOLEAN < BOOLEAN"},
+		{`{"a": 1} > {"a": 0}`, "unknown operator: HASH > HASH"},
+		{"[1, true] < [2, false]", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -234,6 +317,22 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"10 / (5 - 5)",
+			"division by zero",
+		},
+		{
+			"let add = fn(a, b) { a + b }; add(1)",
+			"wrong number of arguments. got=1, want=2",
+		},
+		{
+			"let f = fn() { if (false) { 1 } }; f() + 1",
+			"type mismatch: NULL + INTEGER",
+		},
+		{
+			"[fn() {}()][0] + 1",
+			"type mismatch: NULL + INTEGER",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -250,6 +349,39 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestPanicsAreRecovered(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("boom", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		panic("boom")
+	}})
+
+	program := parser.New(lexer.New("let f = fn() { boom() }; f()")).ParseProgram()
+	evaluated := Eval(program, env)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "internal error: boom" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if !strings.Contains(errObj.Internal, "panic") {
+		t.Errorf("no Go stack in error. got=%q", errObj.Internal)
+	}
+}
+
+func TestUnknownNodes(t *testing.T) {
+	evaluated := Eval(&ast.TypeAnnotation{Name: "int"}, object.NewEnvironment())
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "cannot evaluate *ast.TypeAnnotation" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -266,6 +398,39 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const a = 5; a;", 5},
+		{"const a = 5; let f = fn() { let a = 10; a }; f() + a;", 15},
+		{"const a = 5; let f = fn(a) { a * 2 }; f(a);", 10},
+		{"const a = 5; let a = 6;", "cannot assign to constant a"},
+		{"const a = 5; if (true) { let a = 6; }", "cannot assign to constant a"},
+		{"let a = 5; const a = 6;", "cannot redeclare a as constant"},
+		{"const a = 5; const a = 6;", "cannot assign to constant a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -309,6 +474,55 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x: int = 5; x", 5},
+		{`let greet = fn(name: string, times: int) -> string { name }; greet("hi", 2)`, "hi"},
+		{"let f = fn(x: any, g: fn) -> any { g(x) }; f(1, len) ", "argument to `len` not supported, got INTEGER"},
+		{"let f = fn(xs: array, h: hash, r: range) -> bool { true }; f([], {}, 1..2)", true},
+		{"let f = fn() -> null { }; f()", nil},
+		{"let f = fn(a, b: int) { a }; f(true, 1)", true},
+		{`let x: int = "five";`, "value of x must be int, got STRING"},
+		{`let f = fn(name: string) { name }; f(5)`, "argument name must be string, got INTEGER"},
+		{`let f = fn(a, b: bool) { a }; f(1, 2)`, "argument b must be bool, got INTEGER"},
+		{`let f = fn(x) -> int { x }; f("a")`, "return value must be int, got STRING"},
+		{`let f = fn(x) -> int { if (x) { return "early" }; 1 }; f(true)`, "return value must be int, got STRING"},
+		{`let f = fn() -> string { }; f()`, "return value must be string, got NULL"},
+		{`let f = fn(g: fn) { g() }; f(1)`, "argument g must be fn, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("%q: wrong value. want=%q, got=%q", tt.input, expected, str.Value)
+				}
+				continue
+			}
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. want=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 let newAdder = fn(x) {
@@ -349,6 +563,84 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestDocBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"/// doubles x\nlet double = fn(x) { x * 2 }; doc(double)", "doubles x"},
+		{"let f = fn() { 1 }; doc(f)", ""},
+		{"doc(push)", "push(arr, x) returns a new array with x appended to arr"},
+		{`doc("abc".upper)`, "upper(s) returns s with all letters in upper case"},
+		{"doc(doc)", "doc(f) returns the documentation of a function or builtin"},
+		{"doc(1)", "argument to `doc` must be FUNCTION or BUILTIN, got INTEGER"},
+		{"doc()", "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch result := evaluated.(type) {
+		case *object.String:
+			if result.Value != tt.expected {
+				t.Errorf("wrong doc for %q. want=%q, got=%q", tt.input, tt.expected, result.Value)
+			}
+		case *object.Error:
+			if result.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, result.Message)
+			}
+		default:
+			t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+		}
+	}
+
+	for name, builtin := range builtins {
+		if builtin.Doc == "" {
+			t.Errorf("builtin %s has no doc", name)
+		}
+	}
+}
+
+func TestSlotAccess(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; let f = fn(x) { x + 1 }; f(10)", 11},
+		{"let adder = fn(a) { fn(b) { a + b } }; adder(2)(3)", 5},
+		{"let x = 1; let f = fn() { let y = x; let x = 2; y + x }; f()", 3},
+		{"let f = fn(c) { if (c) { let y = 1 }; y }; f(false)", "identifier not found: y"},
+		{"let f = fn(c) { if (c) { let y = 1 }; y }; f(true)", 1},
+		{"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10)", 55},
+		{`let obj = {"n": 4, "get": fn(d) { self.n + d }}; let d = 100; obj.get(1)`, 5},
+		{`let f = fn(a) { try { throw a } catch (e) { let a = e.value * 2; a } }; f(21)`, 42},
+		{"let f = fn(a) { let g = fn() { a }; let a = 5; g() }; f(1)", 5},
+		{`let obj = {"n": 4, "get": fn() { let h = fn() { self.n }; h() }}; obj.get()`, 4},
+		{`let obj = {"f": fn(self) { self }}; obj.f(7)`, 7},
+		{"let f = fn() { self }; f()", "identifier not found: self"},
+		{"let f = fn() { const a = 1; let a = 2 }; f()", "cannot assign to constant a"},
+		{"let f = fn() { let a = 1; const a = 2 }; f()", "cannot redeclare a as constant"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -496,6 +788,26 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestHashLiteralOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, `{b: 1, a: 2, c: 3}`},
+		{`{3: "x", 1: "y", true: "z"}`, `{3: x, 1: y, true: z}`},
+		{`{"a": 1, "b": 2, "a": 3}`, `{a: 3, b: 2}`},
+		{`{"a": foo, "b": bar}`, `ERROR: identifier not found: foo`},
+		{`try { throw "x" } catch (e) { e }`, `{message: x, type: STRING, position: {line: 1, column: 7}, value: x}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %q. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -541,3 +853,584 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}.foo`, 5},
+		{`{"foo": 5}.bar`, nil},
+		{`let person = {"age": 30}; person.age + 1`, 31},
+		{`let a = {"b": {"c": 2}}; a.b.c`, 2},
+		{`[1, 2, 3].len()`, 3},
+		{`"four".len()`, 4},
+		{`[1, 2].push(3).last()`, 3},
+		{`let l = [1, 2, 3].len; l()`, 3},
+		{`let counter = {"n": 1, "add": fn(x) { self.n + x }}; counter.add(2)`, 3},
+		{`let obj = {"f": len}; obj.f("abc")`, 3},
+		{`5.foo`, "undefined member foo for INTEGER"},
+		{`"abc".foo()`, "undefined method foo for STRING"},
+		{`{"n": 1}.n()`, "not a function: INTEGER"},
+		{`"abc".upper(1)`, "wrong number of arguments. got=1, want=0"},
+		{`"abc".len(1)`, "wrong number of arguments. got=1, want=0"},
+		{`[1].push()`, "wrong number of arguments. got=0, want=1"},
+		{`(1..3).contains(1, 2)`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".upper()`, "ABC"},
+		{`"MiXeD".lower()`, "mixed"},
+		{`let greeter = {"name": "Ann", "greet": fn(g) { g + ", " + self.name }}; greeter.greet("Hi")`, "Hi, Ann"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+}
+
+func TestRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"len(1..10)", 10},
+		{"len(0..<10)", 10},
+		{"len(1..10..3)", 4},
+		{"len(10..1)", 0},
+		{"len(10..1..-1)", 10},
+		{"(1..10)[0]", 1},
+		{"(0..<10..2)[4]", 8},
+		{"(0..<10..2)[5]", nil},
+		{"let n = 3; (0..<n)[-1]", nil},
+		{"first(5..10)", 5},
+		{"last(0..<10..3)", 9},
+		{"first(tail(1..3))", 2},
+		{"last(array(1..4))", 4},
+		{"(1..5).len()", 5},
+		{"let sum = fn(r) { if (len(r) == 0) { 0 } else { first(r) + sum(tail(r)) } }; sum(1..100)", 5050},
+		{"len(0..10..9223372036854775807)", 1},
+		{"len(0..9223372036854775807)", 9223372036854775807},
+		{"if (contains(0..9223372036854775807, 5)) { 1 } else { 0 }", 1},
+		{"if (contains(0..9223372036854775807..9223372036854775807, 9223372036854775807)) { 1 } else { 0 }", 1},
+		{"last(0..9223372036854775807..2)", 9223372036854775806},
+		{"(9223372036854775806..9223372036854775807)[1]", 9223372036854775807},
+		{"len(tail(9223372036854775807..9223372036854775807))", 0},
+		{"len(tail(9223372036854775806..9223372036854775807))", 1},
+		{"len(tail(-9223372036854775807..-9223372036854775807..-1))", 0},
+		{"1..true", "range bound must be INTEGER, got BOOLEAN"},
+		{"1..10..0", "range step must not be zero"},
+		{"len(array(1..100000000000))", "range of 100000000000 integers is too long for an array, the most is 16777216"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestRangeObject(t *testing.T) {
+	evaluated := testEval("2..<8..2")
+	rng, ok := evaluated.(*object.Range)
+	if !ok {
+		t.Fatalf("object is not Range. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if rng.Start != 2 || rng.End != 8 || rng.Step != 2 || !rng.Exclusive {
+		t.Errorf("range has wrong bounds. got=%s", rng.Inspect())
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"contains(1..10, 5)", true},
+		{"contains(0..<10, 10)", false},
+		{"contains(0..10..5, 5)", true},
+		{"contains(0..10..5, 4)", false},
+		{`contains(1..10, "a")`, false},
+		{"contains([1, 2, 3], 2)", true},
+		{`contains([1, "two"], "two")`, true},
+		{"contains([1, 2, 3], 4)", false},
+		{`contains("hello", "ell")`, true},
+		{`contains({"a": 1}, "a")`, true},
+		{`contains({"a": 1}, "b")`, false},
+		{"(1..3).contains(3)", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { 1 + true } catch (e) { 2 }`, 2},
+		{`try { throw "boom"; 1 } catch (e) { e.message }`, "boom"},
+		{`try { throw 5 } catch (e) { e.value + 1 }`, 6},
+		{`try { throw 5 } catch (e) { e.type }`, "INTEGER"},
+		{`try { [1][0] + "a" } catch (e) { e.message }`, "type mismatch: INTEGER + STRING"},
+		{`try { foo } catch (e) { e.type }`, "ERROR"},
+		{`try { len(1) } catch (e) { e.message }`, "argument to `len` not supported, got INTEGER"},
+		{"try {\n  foo\n} catch (e) { e.position.line }", 2},
+		{"try { throw 1 } catch (e) { e.position.column }", 7},
+		{`try { throw 1 } catch (e) { let x = 2; }; x`, "identifier not found: x"},
+		{`try { throw "a" } catch (e) { throw e.message + "b" }`, "ab"},
+		{`throw "uncaught"; 5`, "uncaught"},
+		{`let f = fn() { try { throw 1 } catch (e) { return 2 }; 3 }; f()`, 2},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`try { try { throw 1 } finally { 5 } } catch (e) { e.value }`, 1},
+		{`try { throw 1 } catch (e) { 2 } finally { throw 3 }`, "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestFinallyRunsOnEveryExit(t *testing.T) {
+	tests := []string{
+		`let r = []; try { 1 } finally { let r = push(r, 1) }; r`,
+		`let r = []; try { throw 1 } catch (e) { 2 } finally { let r = push(r, 1) }; r`,
+		`let r = []; try { try { throw 1 } finally { let r = push(r, 1) } } catch (e) { 2 }; r`,
+	}
+
+	for _, input := range tests {
+		evaluated := testEval(input)
+		arr, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if len(arr.Elements) != 1 {
+			t.Errorf("finally did not run. got=%s", arr.Inspect())
+		}
+	}
+
+	input := `let f = fn() { try { return 1 } finally { throw "from finally" } }; f()`
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok || errObj.Message != "from finally" {
+		t.Errorf("finally did not run on return. got=%+v", errObj)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"5 + true", 1, 3},
+		{"let x = 1;\nlet y = x - \"a\";", 2, 11},
+		{"let f = fn() { missing };\n\nf()", 1, 16},
+		{"throw 1", 1, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Line != tt.expectedLine || errObj.Column != tt.expectedColumn {
+			t.Errorf("wrong error position for %q. want=%d:%d, got=%d:%d",
+				tt.input, tt.expectedLine, tt.expectedColumn, errObj.Line, errObj.Column)
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+let apply = fn(f) { f(1, "x") };
+let run = fn() { apply(add) };
+fn() { run() }();`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := []object.Frame{
+		{Function: "add", Line: 4, Column: 22, DefLine: 1, DefColumn: 11},
+		{Function: "apply", Line: 5, Column: 23, DefLine: 4, DefColumn: 13},
+		{Function: "run", Line: 6, Column: 11, DefLine: 5, DefColumn: 11},
+		{Function: "<anonymous>", Line: 6, Column: 15, DefLine: 6, DefColumn: 1},
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack. want=%v, got=%v", expected, errObj.Stack)
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("wrong frame %d. want=%v, got=%v", i, frame, errObj.Stack[i])
+		}
+	}
+
+	traceback := `ERROR: type mismatch: INTEGER + STRING
+	at 2:5
+	in add (defined at 1:11) called at 4:22
+	in apply (defined at 4:13) called at 5:23
+	in run (defined at 5:11) called at 6:11
+	in <anonymous> (defined at 6:1) called at 6:15`
+	if errObj.Traceback() != traceback {
+		t.Errorf("wrong traceback. want=\n%s\ngot=\n%s", traceback, errObj.Traceback())
+	}
+}
+
+func TestTailCallErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(x, y) { x };
+let g = fn() {
+  f(1)
+};
+g();`, `ERROR: wrong number of arguments. got=1, want=2
+	at 3:4
+	in f (defined at 1:9) called at 3:4
+	in g (defined at 2:9) called at 5:2`},
+		{`let f = fn(x) -> string { x };
+let g = fn() {
+  return f(1)
+};
+g();`, `ERROR: return value must be string, got INTEGER
+	at 3:11
+	in f (defined at 1:9) called at 3:11
+	in g (defined at 2:9) called at 5:2`},
+		{`let f = fn(x) { x };
+let g = fn() {
+  let r = f(); r
+};
+g();`, `ERROR: wrong number of arguments. got=0, want=1
+	at 3:12
+	in f (defined at 1:9) called at 3:12
+	in g (defined at 2:9) called at 5:2`},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned for %q", tt.input)
+		}
+		if errObj.Traceback() != tt.expected {
+			t.Errorf("wrong traceback. want=\n%s\ngot=\n%s", tt.expected, errObj.Traceback())
+		}
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	loop := "let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; let g = fn() { f(100); g() }; g()"
+
+	tests := []struct {
+		input    string
+		ctx      func() (context.Context, context.CancelFunc)
+		expected string
+	}{
+		{loop, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation cancelled: context deadline exceeded"},
+		{loop, func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, "evaluation cancelled: context canceled"},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := tt.ctx()
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := EvalContext(ctx, program, object.NewEnvironment())
+		cancel()
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestEvalContextReachesClosures(t *testing.T) {
+	env := object.NewEnvironment()
+	define := "let make = fn() { fn() { 1 } }; let inner = make();"
+	Eval(parser.New(lexer.New(define)).ParseProgram(), env)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated := EvalContext(ctx, parser.New(lexer.New("inner()")).ParseProgram(), env)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "evaluation cancelled: context canceled" {
+		t.Errorf("closure was not cancelled. got=%T(%+v)", evaluated, evaluated)
+	}
+	if env.Context() != nil {
+		t.Errorf("context was not reset after evaluation")
+	}
+
+	testIntegerObject(t, Eval(parser.New(lexer.New("inner()")).ParseProgram(), env), 1)
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   object.Limits
+		expected string
+	}{
+		{"let f = fn(n) { 1 + f(n + 1) }; f(0)", object.Limits{MaxDepth: 50}, "MaxDepth"},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(1000)", object.Limits{MaxDepth: 50}, ""},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } }; f(10); f(10); f(10)", object.Limits{MaxDepth: 12}, ""},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } }; f(100)", object.Limits{MaxSteps: 200}, "MaxSteps"},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } }; f(10)", object.Limits{MaxSteps: 200}, ""},
+		{"push([1, 2], 3)", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"[1, 2, 3]", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"array(1..1000000000000)", object.Limits{MaxArrayLength: 100}, "MaxArrayLength"},
+		{"(1..1000000000000).array()", object.Limits{MaxArrayLength: 100}, "MaxArrayLength"},
+		{"len(1..1000000000000)", object.Limits{MaxArrayLength: 100}, ""},
+		{`let s = "abc"; s + s`, object.Limits{MaxStringLength: 5}, "MaxStringLength"},
+		{`"ab" + "c"`, object.Limits{MaxStringLength: 5}, ""},
+		{`{"a": 1, "b": 2}`, object.Limits{MaxHashSize: 1}, "MaxHashSize"},
+		{"try { [1, 2, 3] } catch (e) { 1 }", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"let f = fn() { try { [1, 2, 3] } finally { return 1 } }; f()", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"let big = fn() { [1, 2, 3] }; let f = fn() { defer big(); throw \"x\" }; f()", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		limits := tt.limits
+		env.SetLimits(&limits)
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		errObj, isErr := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if isErr {
+				t.Errorf("unexpected error for %q: %s", tt.input, errObj.Message)
+			}
+			continue
+		}
+
+		if !isErr {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Limit != tt.expected {
+			t.Errorf("wrong limit for %q. want=%q, got=%q (%s)", tt.input, tt.expected, errObj.Limit, errObj.Message)
+		}
+	}
+}
+
+func TestDeferredCallsStopOnLimitError(t *testing.T) {
+	input := `
+	let fail = fn(msg) { throw msg };
+	let big = fn() { [1, 2, 3] };
+	let f = fn() { defer fail("a"); defer big(); 1 };
+	f()`
+
+	env := object.NewEnvironment()
+	env.SetLimits(&object.Limits{MaxArrayLength: 2})
+
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Limit != "MaxArrayLength" {
+		t.Errorf("wrong limit. want=%q, got=%q", "MaxArrayLength", errObj.Limit)
+	}
+	if errObj.Message != "limit exceeded: MaxArrayLength is 2" {
+		t.Errorf("deferred calls ran after a limit error. got=%q", errObj.Message)
+	}
+}
+
+func TestFinallyRunsOnLimitError(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits object.Limits
+		limit  string
+	}{
+		{"let r = 0; try { [1, 2, 3] } finally { let r = 1 }", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"let r = 0; try { throw 1 } catch (e) { [1, 2, 3] } finally { let r = 1 }", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"let r = 0; try { [1, 2, 3] } finally { let r = 1; throw 2 }", object.Limits{MaxArrayLength: 2}, "MaxArrayLength"},
+		{"let r = 0; let f = fn(n) { 1 + f(n + 1) }; try { f(0) } finally { let r = 1 }", object.Limits{MaxDepth: 20}, "MaxDepth"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		limits := tt.limits
+		env.SetLimits(&limits)
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Limit != tt.limit {
+			t.Errorf("limit error not kept for %q. got=%s", tt.input, evaluated.Inspect())
+		}
+
+		r, _ := env.Get("r")
+		testIntegerObject(t, r, 1)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(100000, 0)", 100000},
+		{"let count = fn(n) { if (n == 0) { return 0 }; return count(n - 1) }; count(100000)", 0},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(100001)", false},
+		{"let sum = fn(xs, acc) { if (len(xs) == 0) { return acc }; sum(xs.tail(), acc + first(xs)) }; sum(array(1..1000), 0)", 500500},
+		{"let loop = fn(n) { if (n > 0) { loop(n - 1) } }; loop(100000)", nil},
+		{"let f = fn(n) { len([n]) }; f(1)", 1},
+		{"let f = fn(x) { x }; let g = fn() { quote(1) }; g()", "QUOTE(1)"},
+		{"let g = fn() { throw \"inner\" }; let f = fn() { defer fail(\"outer\"); g() }; f()", "inner; deferred call failed: outer"},
+		{"let g = fn() { throw \"x\" }; let f = fn() { try { return g() } catch (e) { \"caught\" } }; f()", "caught"},
+		{"let g = fn(n) { \"s\" }; let f = fn(n) -> int { g(n) }; f(1)", "return value must be int, got STRING"},
+		{"let f = fn(n) -> int { if (n == 0) { \"done\" } else { f(n - 1) } }; f(3)", "return value must be int, got STRING"},
+		{"let f = fn(n) { g(n) }; f(1)", "identifier not found: g"},
+		{"let f = fn() { 5() }; f()", "not a function: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		// the calls nest no deeper than this unless tail calls do
+		env.SetLimits(&object.Limits{MaxDepth: 10})
+		env.Set("fail", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Error{Message: args[0].Inspect()}
+		}})
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			got := evaluated.Inspect()
+			if errObj, ok := evaluated.(*object.Error); ok {
+				got = errObj.Message
+			}
+			if got != expected {
+				t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, expected, got)
+			}
+		}
+	}
+}
+
+func TestDeferStatements(t *testing.T) {
+	thrower := "let fail = fn(msg) { throw msg };"
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn() { defer len([]); 1 }; f()", 1},
+		{"let f = fn() { defer fail(\"a\"); defer fail(\"b\"); 1 }; f()", "b; deferred call failed: a"},
+		{"let f = fn() { let x = 1; defer fail(x); let x = 2; x }; f()", "1"},
+		{"let f = fn() { defer fail(\"cleanup\"); 1 + true }; f()", "type mismatch: INTEGER + BOOLEAN; deferred call failed: cleanup"},
+		{"let f = fn() { defer fail(\"c\"); return 1; 2 }; f()", "c"},
+		{"let f = fn() { if (true) { defer fail(\"in if\") }; 1 }; f()", "in if"},
+		{"let f = fn() { try { throw 1 } catch (e) { defer fail(\"in catch\") }; 1 }; f()", "in catch"},
+		{"let f = fn() { defer fail(\"x\"); 1 }; try { f() } catch (e) { len(e.message) }", 1},
+		{"let db = {\"name\": \"db\", \"close\": fn() { fail(\"closed \" + self.name) }}; let f = fn() { defer db.close(); 1 }; f()", "closed db"},
+		{"let inner = fn() { defer fail(\"inner\"); 1 }; let outer = fn() { try { inner() } catch (e) { 2 } }; outer()", 2},
+		{"defer len([])", "defer outside of function"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(thrower + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
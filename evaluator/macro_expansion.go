@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/object"
+)
+
+//DefineMacros removes the top-level macro definitions from program and
+//binds them in env
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if name, macroLiteral, ok := macroDefinition(statement); ok {
+			addMacro(name, macroLiteral, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i = i - 1 {
+		definitionIndex := definitions[i]
+		program.Statements = append(
+			program.Statements[:definitionIndex],
+			program.Statements[definitionIndex+1:]...,
+		)
+	}
+}
+
+func macroDefinition(node ast.Statement) (*ast.Identifier, *ast.MacroLiteral, bool) {
+	var name *ast.Identifier
+	var value ast.Expression
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		name, value = node.Name, node.Value
+	case *ast.ConstStatement:
+		name, value = node.Name, node.Value
+	default:
+		return nil, nil, false
+	}
+
+	macroLiteral, ok := value.(*ast.MacroLiteral)
+	return name, macroLiteral, ok
+}
+
+func addMacro(name *ast.Identifier, macroLiteral *ast.MacroLiteral, env *object.Environment) {
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Env:        env,
+		Body:       macroLiteral.Body,
+	}
+
+	env.Set(name.Value, macro)
+}
+
+//ExpandMacros replaces every call of a macro bound in env with the AST the
+//macro returns, in a copy of program. The first macro that fails to expand is
+//reported as an error.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	var expandErr error
+
+	expanded := ast.Modify(ast.Copy(program), func(node ast.Node) ast.Node {
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok || expandErr != nil {
+			return node
+		}
+
+		macro, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+
+		if len(callExpression.Arguments) != len(macro.Parameters) {
+			expandErr = fmt.Errorf("wrong number of arguments to macro %s. got=%d, want=%d",
+				callExpression.Function, len(callExpression.Arguments), len(macro.Parameters))
+			return node
+		}
+
+		args := quoteArgs(callExpression)
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := Eval(macro.Body, evalEnv)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			expandErr = fmt.Errorf("expanding macro %s: %s", callExpression.Function, errObj.Message)
+			return node
+		}
+
+		quote, ok := unwrapReturnValue(evaluated).(*object.Quote)
+		if !ok {
+			expandErr = fmt.Errorf("macro %s must return a quote, got %s",
+				callExpression.Function, typeOf(evaluated))
+			return node
+		}
+
+		return quote.Node
+	})
+
+	return expanded, expandErr
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return nil, false
+	}
+
+	return macro, true
+}
+
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+	extended := object.NewEnclosedEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
+
+	return extended
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
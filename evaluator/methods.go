@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/literallystan/go-terpreter/object"
+)
+
+//methods holds the methods of the builtin types, keyed by the receiver's ObjectType.
+//The receiver is passed as the first argument.
+var methods = map[object.ObjectType]map[string]*object.Builtin{
+	object.STRING_OBJ: {
+		"len":      builtins["len"],
+		"contains": builtins["contains"],
+		"upper": &object.Builtin{
+			Doc:   "upper(s) returns s with all letters in upper case",
+			Arity: 1,
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return wrongArguments(len(args), 1)
+				}
+
+				str, ok := args[0].(*object.String)
+				if !ok {
+					return newError("argument to `upper` must be STRING, got %s", args[0].Type())
+				}
+				return &object.String{Value: strings.ToUpper(str.Value)}
+			},
+		},
+		"lower": &object.Builtin{
+			Doc:   "lower(s) returns s with all letters in lower case",
+			Arity: 1,
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return wrongArguments(len(args), 1)
+				}
+
+				str, ok := args[0].(*object.String)
+				if !ok {
+					return newError("argument to `lower` must be STRING, got %s", args[0].Type())
+				}
+				return &object.String{Value: strings.ToLower(str.Value)}
+			},
+		},
+	},
+	object.ARRAY_OBJ: {
+		"len":      builtins["len"],
+		"first":    builtins["first"],
+		"last":     builtins["last"],
+		"tail":     builtins["tail"],
+		"push":     builtins["push"],
+		"contains": builtins["contains"],
+	},
+	object.RANGE_OBJ: {
+		"len":      builtins["len"],
+		"first":    builtins["first"],
+		"last":     builtins["last"],
+		"tail":     builtins["tail"],
+		"contains": builtins["contains"],
+		"array":    builtins["array"],
+	},
+}
+
+func lookupMethod(receiver object.Object, name string) (*object.Builtin, bool) {
+	method, ok := methods[receiver.Type()][name]
+	return method, ok
+}
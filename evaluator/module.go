@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/resolver"
+)
+
+//ModuleExt is appended to an import path when no file matches it exactly
+const ModuleExt = ".monkey"
+
+//SearchPathEnv names the environment variable holding the module search path
+const SearchPathEnv = "GOTERPRETER_PATH"
+
+//stdModules are the builtins grouped into modules importable as "std/<name>"
+var stdModules = map[string]*object.Module{
+	"std/strings": newBuiltinModule("std/strings", map[string]*object.Builtin{
+		"len":      builtins["len"],
+		"contains": builtins["contains"],
+		"upper":    methods[object.STRING_OBJ]["upper"],
+		"lower":    methods[object.STRING_OBJ]["lower"],
+	}),
+	"std/arrays": newBuiltinModule("std/arrays", map[string]*object.Builtin{
+		"len":      builtins["len"],
+		"first":    builtins["first"],
+		"last":     builtins["last"],
+		"tail":     builtins["tail"],
+		"push":     builtins["push"],
+		"contains": builtins["contains"],
+		"array":    builtins["array"],
+	}),
+	"std/io": newBuiltinModule("std/io", map[string]*object.Builtin{
+		"print": builtins["print"],
+	}),
+}
+
+func newBuiltinModule(name string, fns map[string]*object.Builtin) *object.Module {
+	env := object.NewEnvironment()
+	for fnName, fn := range fns {
+		env.Set(fnName, fn)
+	}
+	return &object.Module{Name: name, Env: env}
+}
+
+//SearchPath returns the directories listed in GOTERPRETER_PATH
+func SearchPath() []string {
+	return filepath.SplitList(os.Getenv(SearchPathEnv))
+}
+
+//Loader imports modules from files. Every file is evaluated once, in its own
+//environment, and cached by its absolute path.
+type Loader struct {
+	searchPath []string
+	cache      map[string]*object.Module
+	loading    []string
+}
+
+//NewLoader returns a Loader that looks for modules next to the importing
+//file and then in each directory of searchPath
+func NewLoader(searchPath []string) *Loader {
+	return &Loader{
+		searchPath: searchPath,
+		cache:      make(map[string]*object.Module),
+	}
+}
+
+//Import implements object.Importer
+func (l *Loader) Import(name string, env *object.Environment) object.Object {
+	if module, ok := stdModules[name]; ok {
+		return module
+	}
+
+	_, dir := env.Importer()
+	path, ok := l.resolve(name, dir)
+	if !ok {
+		return newError("module not found: %s", name)
+	}
+
+	if module, ok := l.cache[path]; ok {
+		return module
+	}
+
+	for i, loading := range l.loading {
+		if loading == path {
+			cycle := append(append([]string{}, l.loading[i:]...), path)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	l.loading = append(l.loading, path)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	module, errObj := l.load(name, path, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	l.cache[path] = module
+	return module
+}
+
+func (l *Loader) resolve(name, dir string) (string, bool) {
+	candidates := []string{}
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		candidates = append(candidates, filepath.Join(dir, name))
+		for _, p := range l.searchPath {
+			candidates = append(candidates, filepath.Join(p, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, path := range []string{candidate, candidate + ModuleExt} {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+//load evaluates the module at path, with the context and limits of the
+//environment it is imported from
+func (l *Loader) load(name, path string, from *object.Environment) (*object.Module, *object.Error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError("importing %s: %s", name, err)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("importing %s: %s", name, strings.Join(p.Errors(), "; "))
+	}
+
+	macroEnv := object.NewEnvironment()
+	macroEnv.SetContext(from.Context())
+	macroEnv.SetLimits(from.Limits())
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		return nil, newError("importing %s: %s", name, err)
+	}
+
+	r := resolver.New(BuiltinNames()...)
+	r.Resolve(expanded)
+	if len(r.Errors()) != 0 {
+		return nil, newError("importing %s: %s", name, strings.Join(r.Errors(), "; "))
+	}
+
+	env := object.NewEnvironment()
+	env.SetImporter(l, filepath.Dir(path))
+	env.SetContext(from.Context())
+	env.SetLimits(from.Limits())
+
+	if errObj, ok := eval(expanded, env).(*object.Error); ok {
+		wrapped := *errObj
+		wrapped.Message = "importing " + name + ": " + errObj.Message
+		return nil, &wrapped
+	}
+
+	return &object.Module{Name: name, Path: path, Env: env}, nil
+}
+
+func evalImportExpression(name string, env *object.Environment) object.Object {
+	importer, _ := env.Importer()
+	if importer == nil {
+		return newError("import not supported: no module loader")
+	}
+
+	return importer.Import(name, env)
+}
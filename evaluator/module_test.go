@@ -0,0 +1,172 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/literallystan/go-terpreter/object"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "modules")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating %s: %s", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+	}
+
+	return dir
+}
+
+func testEvalModule(input, dir string, searchPath []string) object.Object {
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	env.SetImporter(NewLoader(searchPath), dir)
+
+	return Eval(program, env)
+}
+
+func TestImportExpressions(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/strings.monkey": `
+			let _sep = ", ";
+			let join = fn(a, b) { a + _sep + b };
+			const answer = 42;`,
+		"lib/nested.monkey": `
+			let strings = import "strings";
+			let twice = fn(s) { strings.join(s, s) };`,
+		"lib/fails.monkey":   `let x = 1 + true;`,
+		"lib/broken.monkey":  `let = ;`,
+		"cycle/a.monkey":     `let b = import "b";`,
+		"cycle/b.monkey":     `let a = import "a";`,
+		"shared/util.monkey": `let inc = fn(x) { x + 1 };`,
+	})
+	defer os.RemoveAll(dir)
+
+	searchPath := []string{filepath.Join(dir, "shared")}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let s = import "lib/strings"; s.join("a", "b")`, "a, b"},
+		{`let s = import "lib/strings.monkey"; s.answer`, 42},
+		{`(import "lib/strings").answer + 1`, 43},
+		{`import "lib/strings" == import "lib/strings"`, true},
+		{`let n = import "lib/nested"; n.twice("x")`, "x, x"},
+		{`let u = import "util"; u.inc(1)`, 2},
+		{`let s = import "std/strings"; s.upper("abc")`, "ABC"},
+		{`let a = import "std/arrays"; a.first([7, 8])`, 7},
+		{`let s = import "std/strings"; s.upper(5)`, "argument to `upper` must be STRING, got INTEGER"},
+		{`let s = import "std/strings"; s.lower([])`, "argument to `lower` must be STRING, got ARRAY"},
+		{`let s = import "lib/strings"; s._sep`, "module lib/strings has no export _sep"},
+		{`let s = import "lib/strings"; s.missing`, "module lib/strings has no export missing"},
+		{`import "lib/nope"`, "module not found: lib/nope"},
+		{`import "lib/fails"`, "importing lib/fails: type mismatch: INTEGER + BOOLEAN"},
+		{`import "cycle/a"`, "importing cycle/a: importing b: import cycle: " +
+			filepath.Join(dir, "cycle/a.monkey") + " -> " +
+			filepath.Join(dir, "cycle/b.monkey") + " -> " +
+			filepath.Join(dir, "cycle/a.monkey")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalModule(tt.input, dir, searchPath)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+
+	errObj, ok := testEvalModule(`import "lib/broken"`, dir, nil).(*object.Error)
+	if !ok {
+		t.Fatalf("importing a module with parse errors did not fail")
+	}
+	if errObj.Message[:len("importing lib/broken: ")] != "importing lib/broken: " {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestImportWithoutLoader(t *testing.T) {
+	evaluated := testEval(`import "std/io"`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "import not supported: no module loader" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestStdModulesCheckArguments(t *testing.T) {
+	argLists := [][]object.Object{
+		{},
+		{&object.Integer{Value: 5}},
+		{&object.Integer{Value: 5}, &object.Integer{Value: 6}},
+		{TRUE, NULL, &object.String{Value: "a"}},
+	}
+
+	call := func(fn *object.Builtin, args []object.Object) (panicked interface{}) {
+		defer func() { panicked = recover() }()
+		fn.Fn(args...)
+		return nil
+	}
+
+	for name, module := range stdModules {
+		for _, export := range module.Env.Names() {
+			value, _ := module.Env.Get(export)
+			for _, args := range argLists {
+				if r := call(value.(*object.Builtin), args); r != nil {
+					t.Errorf("%s.%s%v panicked: %v", name, export, args, r)
+				}
+			}
+		}
+	}
+}
+
+func TestImportExpandsMacrosWithLimits(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"deep.monkey": `let m = macro() { let f = fn(n) { 1 + f(n) }; f(1) }; let x = m();`,
+	})
+	defer os.RemoveAll(dir)
+
+	env := object.NewEnvironment()
+	env.SetImporter(NewLoader(nil), dir)
+	env.SetLimits(&object.Limits{MaxDepth: 50})
+
+	evaluated := Eval(testParseProgram(`import "deep"`), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "importing deep: expanding macro m: limit exceeded: MaxDepth is 50"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
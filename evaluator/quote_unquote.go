@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+func quote(node ast.Node, env *object.Environment) object.Object {
+	node = evalUnquoteCalls(node, env)
+	return &object.Quote{Node: node}
+}
+
+//evalUnquoteCalls returns a copy of quoted with its unquote calls replaced by
+//their values. quoted itself is left as is, so that the quote it belongs to
+//can be evaluated again.
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
+	return ast.Modify(ast.Copy(quoted), func(node ast.Node) ast.Node {
+		if !isCallTo(node, "unquote") {
+			return node
+		}
+
+		call := node.(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			return node
+		}
+
+		unquoted := eval(call.Arguments[0], env)
+		if converted := convertObjectToASTNode(unquoted); converted != nil {
+			return converted
+		}
+		return node
+	})
+}
+
+//isCallTo reports whether node is a call of the identifier name, as used by
+//the quote and unquote special forms
+func isCallTo(node ast.Node, name string) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+func convertObjectToASTNode(obj object.Object) ast.Node {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{
+			Type:    token.INT,
+			Literal: fmt.Sprintf("%d", obj.Value),
+		}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}
+
+	case *object.Quote:
+		// a copy, so that the resolver does not reach a node twice when
+		// the same quote is unquoted in two places
+		return ast.Copy(obj.Node)
+
+	default:
+		return nil
+	}
+}
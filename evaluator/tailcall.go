@@ -0,0 +1,223 @@
+package evaluator
+
+import (
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+const tailCallObj = "TAIL_CALL"
+
+//tailCall is a call in tail position: its result is the result of the
+//function making it. It is returned to callFunction instead of being made, so
+//that the call does not nest in the Go stack of the caller's body.
+type tailCall struct {
+	fn   object.Object
+	args []object.Object
+	site token.Token
+}
+
+func (tc *tailCall) Type() object.ObjectType { return tailCallObj }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+//pendingReturn is a function of a chain of tail calls whose return type the
+//result of the chain must have
+type pendingReturn struct {
+	fn     *object.Function
+	site   token.Token // of the tail call of fn, zero for the first call
+	frames int         // the number of frames of the chain up to fn
+}
+
+//callFunction calls fn with args, making the calls in tail position of its
+//body, and then of theirs, in a loop so that tail recursion runs in constant
+//Go stack. A call is in tail position when it is returned or is the last
+//expression of the body, possibly through the branches of an if, but not from
+//within a try. A function with deferred calls pending makes its tail call
+//nested, as they must run after it.
+func callFunction(fn *object.Function, args []object.Object, caller *object.Environment) object.Object {
+	var (
+		frames  []object.Frame // of the tail calls made so far
+		returns []pendingReturn
+		site    token.Token // of the tail call of fn, zero for the first call
+	)
+
+	for {
+		if fn.ReturnType != nil && (len(returns) == 0 || returns[len(returns)-1].fn != fn) {
+			returns = append(returns, pendingReturn{fn: fn, site: site, frames: len(frames)})
+		}
+
+		// an error without a position, e.g. from binding the arguments, is
+		// at the tail call, as the error of a nested call is at its call
+		result := withPosition(applyBody(fn, args, caller), site)
+		tc, ok := result.(*tailCall)
+		if !ok {
+			return finishCall(result, frames, returns)
+		}
+
+		next, ok := tc.fn.(*object.Function)
+		if !ok {
+			result = withFrame(withPosition(applyFunction(tc.fn, tc.args, caller), tc.site), tc.fn, tc.site)
+			return finishCall(result, frames, returns)
+		}
+
+		// a recursive function repeats its frame, which is kept once
+		frame := newFrame(next, tc.site)
+		if len(frames) == 0 || frames[len(frames)-1] != frame {
+			frames = append(frames, frame)
+		}
+		if errObj := checkContext(caller); errObj != nil {
+			return finishCall(withPosition(errObj, tc.site), frames, returns)
+		}
+
+		fn, args, site = next, tc.args, tc.site
+	}
+}
+
+//applyBody evaluates the body of fn called with args. It returns the result
+//of fn, or the tail call fn ends with.
+func applyBody(fn *object.Function, args []object.Object, caller *object.Environment) object.Object {
+	env, errObj := extendFunctionEnv(fn, args)
+	if errObj != nil {
+		return errObj
+	}
+	env.SetContext(caller.Context())
+	env.SetLimits(caller.Limits())
+
+	result := evalTailBlock(fn.Body, env, true)
+	if tc, ok := result.(*tailCall); ok {
+		if len(env.Deferred()) == 0 {
+			return tc
+		}
+		result = withFrame(withPosition(applyFunction(tc.fn, tc.args, env), tc.site), tc.fn, tc.site)
+	}
+
+	return unwrapReturnValue(runDeferredCalls(env, result))
+}
+
+//finishCall completes the result of a chain of tail calls: an error gets their
+//frames, innermost first, and the result must have the return type of every
+//function in the chain. The error of a return type is at the call of its function.
+func finishCall(result object.Object, frames []object.Frame, returns []pendingReturn) object.Object {
+	if errObj, ok := result.(*object.Error); ok {
+		return withFrames(errObj, frames)
+	}
+
+	if result == nil {
+		result = NULL
+	}
+	for i := len(returns) - 1; i >= 0; i-- {
+		r := returns[i]
+		result = checkReturnType(r.fn, result)
+		if errObj, ok := result.(*object.Error); ok {
+			withPosition(errObj, r.site)
+			return withFrames(errObj, frames[:r.frames])
+		}
+	}
+	return result
+}
+
+//withFrames adds frames to the stack of errObj, the last one first
+func withFrames(errObj *object.Error, frames []object.Frame) *object.Error {
+	for i := len(frames) - 1; i >= 0; i-- {
+		errObj.Stack = append(errObj.Stack, frames[i])
+	}
+	return errObj
+}
+
+//evalTailBlock evaluates a block of a function body. Its return statements are
+//in tail position, and so is its last statement if last is set.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment, last bool) object.Object {
+	if errObj := checkContext(env); errObj != nil {
+		return errObj
+	}
+	if errObj := step(env); errObj != nil {
+		return errObj
+	}
+
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		result = evalTailStatement(statement, env, last && i == len(block.Statements)-1)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == tailCallObj {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func evalTailStatement(stmt ast.Statement, env *object.Environment, last bool) object.Object {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		if call, ok := stmt.ReturnValue.(*ast.CallExpression); ok {
+			if errObj := step(env); errObj != nil {
+				return errObj
+			}
+			return evalTailCall(call, env)
+		}
+	case *ast.ExpressionStatement:
+		switch exp := stmt.Expression.(type) {
+		case *ast.IfExpression:
+			if errObj := step(env); errObj != nil {
+				return errObj
+			}
+			return evalTailIf(exp, env, last)
+		case *ast.CallExpression:
+			if last {
+				if errObj := step(env); errObj != nil {
+					return errObj
+				}
+				return evalTailCall(exp, env)
+			}
+		}
+	}
+	return eval(stmt, env)
+}
+
+func evalTailIf(ie *ast.IfExpression, env *object.Environment, last bool) object.Object {
+	if errObj := step(env); errObj != nil {
+		return errObj
+	}
+
+	condition := eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	var result object.Object
+	if isTruthy(condition) {
+		result = evalTailBlock(ie.Consequence, env, last)
+	} else if ie.Alternative != nil {
+		result = evalTailBlock(ie.Alternative, env, last)
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+//evalTailCall evaluates the function and arguments of a call in tail position
+//and returns the call to be made
+func evalTailCall(call *ast.CallExpression, env *object.Environment) object.Object {
+	if errObj := step(env); errObj != nil {
+		return errObj
+	}
+	if isCallTo(call, "quote") {
+		return eval(call, env)
+	}
+
+	function := evalCallee(call.Function, env)
+	if isError(function) {
+		return withPosition(function, call.Token)
+	}
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return &tailCall{fn: function, args: args, site: call.Token}
+}
@@ -0,0 +1,461 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Source formats a program in canonical style: one statement per line, blocks
+//indented with tabs, no parentheses the parser does not need, comments kept and
+//at most one blank line between statements. Formatting a formatted program
+//gives the same program back.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	pr := &printer{comments: l.Comments()}
+	pr.scan(string(src))
+	pr.program(program)
+	out := pr.buf.Bytes()
+
+	// the formatted program has to parse into the same tree
+	l = lexer.New(string(out))
+	p = parser.New(l)
+	reparsed := p.ParseProgram()
+	if len(p.Errors()) != 0 || reparsed.String() != program.String() {
+		return nil, fmt.Errorf("formatting changed the program")
+	}
+
+	return out, nil
+}
+
+//Node formats a tree that has no source, such as one built by a macro. Any
+//comments are lost.
+func Node(node ast.Node) string {
+	pr := &printer{}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+	case ast.Statement:
+		pr.statement(node, nil)
+	case ast.Expression:
+		pr.expression(node)
+	}
+
+	return pr.buf.String()
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+
+	comments []token.Token
+	next     int // index of the first comment not printed yet
+
+	tokens []token.Token // the tokens of the source, used to place comments
+	index  map[position]int
+}
+
+type position struct {
+	line, column int
+}
+
+func pos(tok token.Token) position {
+	return position{tok.Line, tok.Column}
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+//scan collects the tokens of src, including the final EOF
+func (p *printer) scan(src string) {
+	p.index = make(map[position]int)
+
+	l := lexer.New(src)
+	for {
+		tok := l.NextToken()
+		p.index[pos(tok)] = len(p.tokens)
+		p.tokens = append(p.tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+}
+
+func (p *printer) hasSource() bool {
+	return p.tokens != nil
+}
+
+//lastTokenBefore returns the last source token before end
+func (p *printer) lastTokenBefore(end token.Token) token.Token {
+	i := sort.Search(len(p.tokens), func(i int) bool { return !before(p.tokens[i], end) })
+	if i == 0 {
+		return token.Token{}
+	}
+	return p.tokens[i-1]
+}
+
+//closingBrace returns the } matching the { token lbrace
+func (p *printer) closingBrace(lbrace token.Token) token.Token {
+	i, ok := p.index[pos(lbrace)]
+	if !p.hasSource() || !ok {
+		return token.Token{}
+	}
+
+	depth := 0
+	for ; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 {
+				return p.tokens[i]
+			}
+		}
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.write("\n")
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat("\t", p.indent))
+}
+
+//commentsBefore prints the comments before end on lines of their own. prevLine
+//is the source line the previous statement or comment ended on, or 0 at the
+//start of a block. A blank line is kept where the source had one or more.
+func (p *printer) commentsBefore(end token.Token, prevLine int) int {
+	for p.next < len(p.comments) && before(p.comments[p.next], end) {
+		c := p.comments[p.next]
+		if prevLine > 0 && c.Line > prevLine+1 {
+			p.newline()
+		}
+		p.writeIndent()
+		p.write(c.Literal)
+		p.newline()
+
+		// a comment moved out of an expression can be above prevLine
+		if c.Line > prevLine {
+			prevLine = c.Line
+		}
+		p.next++
+	}
+	return prevLine
+}
+
+//trailingComment prints a comment on the line of last, after it, on the same line
+func (p *printer) trailingComment(last, end token.Token) {
+	if p.next >= len(p.comments) {
+		return
+	}
+
+	c := p.comments[p.next]
+	if c.Line == last.Line && before(last, c) && before(c, end) {
+		p.write(" ")
+		p.write(c.Literal)
+		p.next++
+	}
+}
+
+func (p *printer) program(program *ast.Program) {
+	end := token.Token{Line: int(^uint(0) >> 1)}
+	if p.hasSource() {
+		end = p.tokens[len(p.tokens)-1]
+	}
+
+	p.statements(program.Statements, end)
+}
+
+//statements prints one statement per line, followed by the comments before end
+func (p *printer) statements(stmts []ast.Statement, end token.Token) {
+	prevLine := 0
+
+	for i, stmt := range stmts {
+		start := startToken(stmt)
+		boundary := end
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+			boundary = startToken(next)
+		}
+
+		if p.hasSource() {
+			prevLine = p.commentsBefore(start, prevLine)
+			if prevLine > 0 && start.Line > prevLine+1 {
+				p.newline()
+			}
+		}
+
+		p.writeIndent()
+		p.statement(stmt, next)
+
+		if p.hasSource() {
+			last := p.lastTokenBefore(boundary)
+			p.trailingComment(last, boundary)
+			prevLine = last.Line
+		}
+		p.newline()
+	}
+
+	if p.hasSource() {
+		p.commentsBefore(end, prevLine)
+	}
+}
+
+func startToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ConstStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ThrowStatement:
+		return stmt.Token
+	case *ast.DeferStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
+//statement prints stmt without indentation or newline. next is the statement
+//that follows it, if any.
+func (p *printer) statement(stmt ast.Statement, next ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value)
+		if stmt.Type != nil {
+			p.write(": " + stmt.Type.Name)
+		}
+		p.write(" = ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ConstStatement:
+		p.write("const " + stmt.Name.Value + " = ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmt.ReturnValue)
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.DeferStatement:
+		p.write("defer ")
+		p.expression(stmt.Call)
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		if needsSemicolon(stmt, next) {
+			p.write(";")
+		}
+	}
+}
+
+//needsSemicolon reports whether stmt has to end in a semicolon. An if or try
+//statement ends in a block and only needs one when the next statement could
+//otherwise be parsed as its continuation, e.g. as a call.
+func needsSemicolon(stmt *ast.ExpressionStatement, next ast.Statement) bool {
+	switch stmt.Expression.(type) {
+	case *ast.IfExpression, *ast.TryExpression:
+		_, ok := next.(*ast.ExpressionStatement)
+		return ok
+	}
+	return true
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	end := p.closingBrace(block.Token)
+
+	hasComments := p.next < len(p.comments) && before(p.comments[p.next], end)
+	if len(block.Statements) == 0 && !hasComments {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	p.newline()
+	p.indent++
+	p.statements(block.Statements, end)
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+}
+
+//atom is the precedence of expressions that never need parentheses
+const atom = parser.INDEX + 1
+
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.RangeExpression:
+		return parser.RANGE
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	}
+	return atom
+}
+
+//operand prints exp, in parentheses if it binds less tightly than min
+func (p *printer) operand(exp ast.Expression, min int) {
+	if precedence(exp) < min {
+		p.write("(")
+		p.expression(exp)
+		p.write(")")
+		return
+	}
+	p.expression(exp)
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		p.write(exp.Token.Literal)
+	case *ast.Boolean:
+		p.write(exp.Token.Literal)
+	case *ast.StringLiteral:
+		p.write(`"` + exp.Value + `"`)
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		p.operand(exp.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		prec := parser.Precedence(exp.Token.Type)
+		p.operand(exp.Left, prec)
+		p.write(" " + exp.Operator + " ")
+		// operators are left associative, so an equal right operand is grouped
+		p.operand(exp.Right, prec+1)
+	case *ast.RangeExpression:
+		p.operand(exp.Start, parser.RANGE+1)
+		p.write(exp.Token.Literal)
+		p.operand(exp.End, parser.RANGE+1)
+		if exp.Step != nil {
+			p.write("..")
+			p.operand(exp.Step, parser.RANGE+1)
+		}
+	case *ast.CallExpression:
+		// calls, indexes and member accesses chain from left to right
+		p.operand(exp.Function, parser.CALL)
+		p.write("(")
+		p.expressions(exp.Arguments)
+		p.write(")")
+	case *ast.IndexExpression:
+		p.operand(exp.Left, parser.CALL)
+		p.write("[")
+		p.expression(exp.Index)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.operand(exp.Object, parser.CALL)
+		p.write("." + exp.Property.Value)
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.expressions(exp.Elements)
+		p.write("]")
+	case *ast.HashLiteral:
+		p.write("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(pair.Key)
+			p.write(": ")
+			p.expression(pair.Value)
+		}
+		p.write("}")
+	case *ast.FunctionLiteral:
+		p.write("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.write(param.Value)
+			if typ := exp.ParamType(i); typ != nil {
+				p.write(": " + typ.Name)
+			}
+		}
+		p.write(") ")
+		if exp.ReturnType != nil {
+			p.write("-> " + exp.ReturnType.Name + " ")
+		}
+		p.block(exp.Body)
+	case *ast.MacroLiteral:
+		p.write("macro(")
+		p.parameters(exp.Parameters)
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(exp.Condition)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(exp.Block)
+		if exp.Catch != nil {
+			p.write(" catch (" + exp.CatchParam.Value + ") ")
+			p.block(exp.Catch)
+		}
+		if exp.Finally != nil {
+			p.write(" finally ")
+			p.block(exp.Finally)
+		}
+	case *ast.ImportExpression:
+		p.write("import ")
+		p.expression(exp.Path)
+	default:
+		p.write(exp.String())
+	}
+}
+
+func (p *printer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(exp)
+	}
+}
+
+func (p *printer) parameters(params []*ast.Identifier) {
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+	}
+}
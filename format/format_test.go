@@ -0,0 +1,140 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"let x = (1 + 2) * 3;", "let x = (1 + 2) * 3;\n"},
+		{"((a + b) + c)", "a + b + c;\n"},
+		{"a + (b + c)", "a + (b + c);\n"},
+		{"a - (b - c)", "a - (b - c);\n"},
+		{"(a * b) - (c / d)", "a * b - c / d;\n"},
+		{"-(a + b)", "-(a + b);\n"},
+		{"-(a.b)", "-a.b;\n"},
+		{"(-a).b", "(-a).b;\n"},
+		{"((a.b)(c))[d].e", "a.b(c)[d].e;\n"},
+		{"!(a == b)", "!(a == b);\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{"(f(x))[0]", "f(x)[0];\n"},
+		{"(a + b)(c)", "(a + b)(c);\n"},
+		{"(1 + 2)..(10 * 2)", "1 + 2..10 * 2;\n"},
+		{"(0..10)..2", "(0..10)..2;\n"},
+		{"0..<(a == b)", "0..<(a == b);\n"},
+		{"1..10..2", "1..10..2;\n"},
+		{`{"a":1,  "b" : [1,2]}`, "{\"a\": 1, \"b\": [1, 2]};\n"},
+		{"let f = fn(x,y){x+y}", "let f = fn(x, y) {\n\tx + y;\n};\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+		{"let n:int=f(1)", "let n: int = f(1);\n"},
+		{"let f = fn(a:int,b)->string{a}", "let f = fn(a: int, b) -> string {\n\ta;\n};\n"},
+		{"if (a) { b } else { c }", "if (a) {\n\tb;\n} else {\n\tc;\n}\n"},
+		{"if (a) { b };\n(c)", "if (a) {\n\tb;\n};\nc;\n"},
+		{"if (a) { b }\nlet c = 1", "if (a) {\n\tb;\n}\nlet c = 1;\n"},
+		{"try { a } catch(e) { b } finally { c }",
+			"try {\n\ta;\n} catch (e) {\n\tb;\n} finally {\n\tc;\n}\n"},
+		{"let f = fn() { defer g(1); throw 2; return 3 }",
+			"let f = fn() {\n\tdefer g(1);\n\tthrow 2;\n\treturn 3;\n};\n"},
+		{"const m = import \"std/io\"; m.print(1)", "const m = import \"std/io\";\nm.print(1);\n"},
+		{"let u = macro(a) { quote(unquote(a)) }", "let u = macro(a) {\n\tquote(unquote(a));\n};\n"},
+		{"a;\n\n\n\nb;c;", "a;\n\nb;\nc;\n"},
+		{"// top\nlet a = 1; // one\n\n/// doc\nlet f = fn() {\n  // inside\n  1 // value\n  // end\n};\n// bottom",
+			"// top\nlet a = 1; // one\n\n/// doc\nlet f = fn() {\n\t// inside\n\t1; // value\n\t// end\n};\n// bottom\n"},
+		{"let f = fn() {\n// only\n}", "let f = fn() {\n\t// only\n};\n"},
+		{"let h = {\n\"a\": 1, // first\n\"b\": 2\n};\nh", "let h = {\"a\": 1, \"b\": 2};\n// first\nh;\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) failed: %s", tt.input, err)
+			continue
+		}
+
+		if string(formatted) != tt.expected {
+			t.Errorf("wrong format for %q.\nwant=%q\ngot=%q", tt.input, tt.expected, formatted)
+		}
+
+		again, err := Source(formatted)
+		if err != nil {
+			t.Errorf("Source(%q) failed: %s", formatted, err)
+			continue
+		}
+		if string(again) != string(formatted) {
+			t.Errorf("format is not idempotent for %q.\nfirst=%q\nsecond=%q", tt.input, formatted, again)
+		}
+	}
+}
+
+func TestSourceKeepsMeaning(t *testing.T) {
+	input := `let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+let r = -(1 + 2) * 3 - (4 - 5) / (6 * 7);
+let s = {"a": [1, 2][0], "b": (fn(x) { x })(1)}["b"];
+!(r == s) == (r < s);
+0..(r + 1)..<2;`
+
+	formatted, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source failed: %s", err)
+	}
+
+	original := parser.New(lexer.New(input)).ParseProgram()
+	reparsed := parser.New(lexer.New(string(formatted))).ParseProgram()
+
+	if original.String() != reparsed.String() {
+		t.Errorf("meaning changed.\nwant=%q\ngot=%q", original.String(), reparsed.String())
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 1;"))
+	if err == nil {
+		t.Fatalf("expected an error for a program that does not parse")
+	}
+}
+
+func TestNode(t *testing.T) {
+	program := parser.New(lexer.New("let x = fn(a) { (a + 1) * 2 }; x(1)")).ParseProgram()
+
+	expected := "let x = fn(a) {\n\t(a + 1) * 2;\n};\nx(1);\n"
+	if formatted := Node(program); formatted != expected {
+		t.Errorf("wrong format. want=%q, got=%q", expected, formatted)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+	b := []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n")
+
+	expected := `--- f
++++ f
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+
+	if diff := Diff("f", a, b); string(diff) != expected {
+		t.Errorf("wrong diff.\nwant=%s\ngot=%s", expected, diff)
+	}
+
+	if diff := Diff("f", a, a); diff != nil {
+		t.Errorf("expected no diff for equal input. got=%s", diff)
+	}
+}
@@ -1,6 +1,11 @@
 package lexer
 
-import "github.com/literallystan/go-terpreter/token"
+import (
+	"sort"
+	"strings"
+
+	"github.com/literallystan/go-terpreter/token"
+)
 
 //Lexer ...
 type Lexer struct {
@@ -8,11 +13,65 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int // line and column of ch
+	column       int
+
+	operators []operator // registered operators, longest first
+
+	docs     map[int]string // /// comment blocks, keyed by the line they end on
+	comments []token.Token
+}
+
+type operator struct {
+	literal   string
+	tokenType token.TokenType
+}
+
+//RegisterOperator makes this lexer emit a token of type t for literal. Registered
+//operators take priority over the builtin ones, and longer literals over shorter.
+//Tokens already read are not affected: once the lexer is passed to a parser,
+//which reads ahead, register operators with Parser.RegisterOperator instead.
+func (l *Lexer) RegisterOperator(literal string, t token.TokenType) {
+	l.operators = append(l.operators, operator{literal: literal, tokenType: t})
+	sort.SliceStable(l.operators, func(i, j int) bool {
+		return len(l.operators[i].literal) > len(l.operators[j].literal)
+	})
+}
+
+//Rewind makes the lexer read again from tok, a token it has returned. The
+//comments after tok are forgotten, to be read again as well.
+func (l *Lexer) Rewind(tok token.Token) {
+	offset := 0
+	for line := 1; line < tok.Line && offset < len(l.input); line++ {
+		next := strings.IndexByte(l.input[offset:], '\n')
+		if next < 0 {
+			offset = len(l.input)
+			break
+		}
+		offset += next + 1
+	}
+	offset += tok.Column - 1
+	if offset > len(l.input) {
+		offset = len(l.input)
+	}
+
+	kept := 0
+	for _, comment := range l.comments {
+		if comment.Line < tok.Line || comment.Line == tok.Line && comment.Column < tok.Column {
+			kept++
+		}
+	}
+	l.comments = l.comments[:kept]
+
+	l.readPosition = offset
+	l.line, l.column = tok.Line, tok.Column-1
+	l.ch = 0
+	l.readChar()
 }
 
 //New - returns a new pointer to a lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1, docs: make(map[int]string)}
 	l.readChar()
 	return l
 }
@@ -23,6 +82,15 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+
+	if op, ok := l.matchOperator(); ok {
+		for i := 0; i < len(op.literal); i++ {
+			l.readChar()
+		}
+		return token.Token{Type: op.tokenType, Literal: op.literal, Line: line, Column: column}
+	}
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -36,7 +104,12 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "->"}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -72,6 +145,18 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '<' {
+				l.readChar()
+				tok = token.Token{Type: token.RANGE_EXCL, Literal: "..<"}
+			} else {
+				tok = token.Token{Type: token.RANGE, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
@@ -82,19 +167,36 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
+			tok.Line, tok.Column = line, column
 			return tok
 		}
 		tok = newToken(token.ILLEGAL, l.ch)
 	}
 
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
+func (l *Lexer) matchOperator() (operator, bool) {
+	if l.position >= len(l.input) {
+		return operator{}, false
+	}
+
+	rest := l.input[l.position:]
+	for _, op := range l.operators {
+		if op.literal != "" && strings.HasPrefix(rest, op.literal) {
+			return op, true
+		}
+	}
+	return operator{}, false
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) {
@@ -115,6 +217,12 @@ func newToken(tokenType token.TokenType, ch byte) token.Token {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -133,9 +241,51 @@ func (l *Lexer) readNumber() string {
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+//skipComment skips a // comment up to the end of the line. The text of a ///
+//doc comment is kept, joined with any doc comment on the line above.
+func (l *Lexer) skipComment() {
+	line, column := l.line, l.column
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+
+	text := strings.TrimRight(l.input[position:l.position], "\r")
+	l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: text, Line: line, Column: column})
+
+	if !strings.HasPrefix(text, "///") {
+		return
+	}
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "///"), " ")
+
+	if above, ok := l.docs[line-1]; ok {
+		delete(l.docs, line-1)
+		text = above + "\n" + text
+	}
+	l.docs[line] = text
+}
+
+//Comments returns the comments the lexer has skipped so far, in source order
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+//DocBefore returns the /// comment block ending on the line above line, or ""
+//if there is none. Only comments the lexer has already passed are known.
+func (l *Lexer) DocBefore(line int) string {
+	return l.docs[line-1]
 }
 
 func (l *Lexer) peekChar() byte {
@@ -26,6 +26,12 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"}
+	person.name;
+	1..10..2;
+	0..<n;
+	try catch finally throw defer
+	import "lib/strings";
+	fn(x: int) -> int
 `
 	tests := []struct {
 		expectedType    token.TokenType
@@ -117,6 +123,36 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "person"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.INT, "10"},
+		{token.RANGE, ".."},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "0"},
+		{token.RANGE_EXCL, "..<"},
+		{token.IDENT, "n"},
+		{token.SEMICOLON, ";"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.DEFER, "defer"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/strings"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
 		{token.EOF, ""},
 	}
 
@@ -136,3 +172,166 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "a b";`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"a b", 2, 7},
+		{";", 2, 12},
+		{"", 2, 13},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	input := `a ** b |> c * d == e`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{"POW", "**"},
+		{token.IDENT, "b"},
+		{"PIPE", "|>"},
+		{token.IDENT, "c"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "d"},
+		{token.EQ, "=="},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.RegisterOperator("|>", "PIPE")
+	l.RegisterOperator("**", "POW")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if tok := New("a ** b").NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("operator registered on another lexer. got=%q", tok.Type)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	l := New("a // one\n  ** b")
+	l.NextToken()
+	star := l.NextToken()
+
+	l.RegisterOperator("**", "POW")
+	l.Rewind(star)
+
+	tok := l.NextToken()
+	if tok.Type != "POW" || tok.Line != 2 || tok.Column != 3 {
+		t.Fatalf("wrong token after Rewind. got=%+v", tok)
+	}
+	if tok := l.NextToken(); tok.Literal != "b" {
+		t.Fatalf("wrong token after operator. got=%+v", tok)
+	}
+	if len(l.Comments()) != 1 {
+		t.Fatalf("comment before the token forgotten. got=%v", l.Comments())
+	}
+
+	l.Rewind(token.Token{Line: 1, Column: 1})
+	if tok := l.NextToken(); tok.Literal != "a" || len(l.Comments()) != 0 {
+		t.Fatalf("wrong state after Rewind to the start. got=%+v, %v", tok, l.Comments())
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a plain comment
+/// adds two numbers
+/// and returns the sum
+let add = 1; // trailing
+
+/// stray
+let x = 2 / 1;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "add"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "2"},
+		{token.SLASH, "/"},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	docs := map[int]string{
+		1: "",
+		4: "adds two numbers\nand returns the sum",
+		5: "",
+		7: "stray",
+	}
+
+	for line, expected := range docs {
+		if doc := l.DocBefore(line); doc != expected {
+			t.Errorf("DocBefore(%d) wrong. expected=%q, got=%q", line, expected, doc)
+		}
+	}
+}
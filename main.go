@@ -1,48 +1,350 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/literallystan/gopiler/evaluator"
-	"github.com/literallystan/gopiler/lexer"
-	"github.com/literallystan/gopiler/object"
-	"github.com/literallystan/gopiler/parser"
-	"github.com/literallystan/gopiler/repl"
+	"github.com/literallystan/go-terpreter/analysis"
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/evaluator"
+	"github.com/literallystan/go-terpreter/format"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/optimize"
+	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/repl"
+	"github.com/literallystan/go-terpreter/resolver"
+	"github.com/literallystan/go-terpreter/types"
 )
 
+const usage = `usage:
+	go-terpreter                        start the repl
+	go-terpreter [--no-optimize] [--timeout duration] <file>
+	                                    run a script, --no-optimize skips constant folding,
+	                                    --timeout stops it after a duration such as 5s
+	go-terpreter expand <file>          print a script with its macros expanded
+	go-terpreter ast [--json] <file>    print the syntax tree of a script, as JSON with --json
+	go-terpreter fmt [-w|-d] <file>...  format scripts, -w writes them back, -d prints a diff
+	go-terpreter lint [-enable rules] [-disable rules] <file>...
+	                                    report likely mistakes, rules are comma separated
+	go-terpreter check <file>...        report type errors without running scripts
+`
+
 func main() {
 	out := os.Stdout
-	if len(os.Args) >= 3 {
-		fmt.Printf("Wanted 1 arg, got {%d}\n", len(os.Args)-1)
+	args := os.Args[1:]
+
+	switch {
+	case len(args) == 0:
+		fmt.Printf("Feel free to type in commands\n")
+		repl.Start(os.Stdin, os.Stdout)
+	case len(args) == 2 && args[0] == "expand":
+		runExpand(out, args[1])
+	case len(args) == 2 && args[0] == "ast":
+		runAST(out, args[1], false)
+	case len(args) == 3 && args[0] == "ast" && args[1] == "--json":
+		runAST(out, args[2], true)
+	case len(args) >= 2 && args[0] == "fmt":
+		runFmt(out, args[1:])
+	case len(args) >= 2 && args[0] == "lint":
+		if !runLint(out, args[1:]) {
+			os.Exit(1)
+		}
+	case len(args) >= 2 && args[0] == "check":
+		if !runCheck(out, args[1:]) {
+			os.Exit(1)
+		}
+	case len(args) == 1 || strings.HasPrefix(args[0], "--"):
+		opts, rest, err := parseRunArgs(args)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			io.WriteString(out, usage)
+			return
+		}
+		if len(rest) != 1 {
+			fmt.Printf("Wanted 1 arg, got {%d}\n", len(rest))
+			io.WriteString(out, usage)
+			return
+		}
+		runFile(out, rest[0], opts)
+	default:
+		fmt.Printf("Wanted 1 arg, got {%d}\n", len(args))
+		io.WriteString(out, usage)
+	}
+}
+
+//runOptions are the flags given before the path of a script to run
+type runOptions struct {
+	optimize bool
+	timeout  time.Duration // 0 for none
+}
+
+//parseRunArgs reads the flags at the start of args and returns the rest
+func parseRunArgs(args []string) (runOptions, []string, error) {
+	opts := runOptions{optimize: true}
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		switch {
+		case args[0] == "--no-optimize":
+			opts.optimize = false
+			args = args[1:]
+		case args[0] == "--timeout" && len(args) >= 2:
+			timeout, err := time.ParseDuration(args[1])
+			if err != nil {
+				return opts, nil, fmt.Errorf("invalid timeout %q", args[1])
+			}
+			opts.timeout = timeout
+			args = args[2:]
+		default:
+			return opts, nil, fmt.Errorf("unknown flag %s", args[0])
+		}
+	}
+	return opts, args, nil
+}
+
+func runFile(out io.Writer, path string, opts runOptions) {
+	program, ok := parseFile(out, path)
+	if !ok {
+		return
+	}
+
+	env := object.NewEnvironment()
+	env.SetImporter(evaluator.NewLoader(evaluator.SearchPath()), filepath.Dir(path))
+	env.SetLimits(newLimits())
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	expanded, ok := expandMacros(ctx, out, program, env.Limits())
+	if !ok {
+		return
+	}
+
+	if !resolve(out, expanded) {
+		return
+	}
+
+	// after resolving, so that names undefined in a pruned branch are still reported
+	if opts.optimize {
+		expanded = optimize.Program(expanded.(*ast.Program))
+	}
+
+	evaluated := evaluator.EvalContext(ctx, expanded, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		io.WriteString(out, errObj.Traceback())
+		io.WriteString(out, "\n")
+	} else if evaluated != nil {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+}
+
+func runExpand(out io.Writer, path string) {
+	program, ok := parseFile(out, path)
+	if !ok {
+		return
+	}
+
+	expanded, ok := expandMacros(context.Background(), out, program, newLimits())
+	if !ok {
+		return
+	}
+
+	io.WriteString(out, format.Node(expanded))
+}
+
+func runAST(out io.Writer, path string, asJSON bool) {
+	program, ok := parseFile(out, path)
+	if !ok {
+		return
+	}
+
+	if !asJSON {
+		io.WriteString(out, program.String())
+		io.WriteString(out, "\n")
+		return
+	}
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		fmt.Fprintln(out, err)
 		return
-	} else if len(os.Args) == 2 {
-		env := object.NewEnvironment()
-		p, err := ioutil.ReadFile(os.Args[1])
+	}
+
+	out.Write(data)
+	io.WriteString(out, "\n")
+}
+
+func runFmt(out io.Writer, args []string) {
+	mode := ""
+	if args[0] == "-w" || args[0] == "-d" {
+		mode, args = args[0], args[1:]
+	}
+
+	for _, path := range args {
+		src, err := ioutil.ReadFile(path)
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(out, err)
+			continue
 		}
 
-		l := lexer.New(string(p))
-		parse := parser.New(l)
+		formatted, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s\n", path, err)
+			continue
+		}
 
-		program := parse.ParseProgram()
-		if len(parse.Errors()) != 0 {
-			printParserErrors(out, parse.Errors())
-			return
+		switch mode {
+		case "-w":
+			if bytes.Equal(src, formatted) {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err == nil {
+				err = ioutil.WriteFile(path, formatted, info.Mode())
+			}
+			if err != nil {
+				fmt.Fprintln(out, err)
+			}
+		case "-d":
+			out.Write(format.Diff(path, src, formatted))
+		default:
+			out.Write(formatted)
 		}
+	}
+}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+//runLint reports what the rules find in each file, and whether there was nothing
+func runLint(out io.Writer, args []string) bool {
+	config := analysis.Config{}
+	for len(args) >= 2 && (args[0] == "-enable" || args[0] == "-disable") {
+		names := strings.Split(args[1], ",")
+		if args[0] == "-enable" {
+			config.Enabled = append(config.Enabled, names...)
+		} else {
+			config.Disabled = append(config.Disabled, names...)
+		}
+		args = args[2:]
+	}
+
+	rules, err := config.Rules()
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return false
+	}
+
+	clean := true
+	for _, path := range args {
+		program, ok := parseFile(out, path)
+		if !ok {
+			clean = false
+			continue
+		}
+
+		for _, d := range analysis.Run(program, rules, evaluator.BuiltinNames()) {
+			fmt.Fprintf(out, "%s:%s\n", path, d)
+			clean = false
+		}
+	}
+	return clean
+}
+
+//runCheck reports the type errors of each file, and whether there were none
+func runCheck(out io.Writer, paths []string) bool {
+	clean := true
+	for _, path := range paths {
+		program, ok := parseFile(out, path)
+		if !ok {
+			clean = false
+			continue
+		}
+
+		expanded, ok := expandMacros(context.Background(), out, program, newLimits())
+		if !ok {
+			clean = false
+			continue
+		}
+
+		checker := types.New()
+		checker.Check(expanded)
+		for _, msg := range checker.Errors() {
+			fmt.Fprintf(out, "%s:%s\n", path, msg)
+			clean = false
 		}
-	} else {
-		fmt.Printf("Feel free to type in commands\n")
-		repl.Start(os.Stdin, os.Stdout)
 	}
+	return clean
+}
+
+func parseFile(out io.Writer, path string) (*ast.Program, bool) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return nil, false
+	}
+
+	l := lexer.New(string(p))
+	parse := parser.New(l)
+
+	program := parse.ParseProgram()
+	if len(parse.Errors()) != 0 {
+		printParserErrors(out, parse.Errors())
+		return nil, false
+	}
+
+	return program, true
+}
+
+//newLimits returns the limits scripts are run with
+func newLimits() *object.Limits {
+	return &object.Limits{MaxDepth: object.DefaultDepth, MaxArrayLength: object.DefaultArrayLength}
+}
+
+//expandMacros expands the macros of program, evaluating them with ctx and
+//limits like the program itself
+func expandMacros(ctx context.Context, out io.Writer, program *ast.Program, limits *object.Limits) (ast.Node, bool) {
+	macroEnv := object.NewEnvironment()
+	macroEnv.SetContext(ctx)
+	macroEnv.SetLimits(limits)
+	evaluator.DefineMacros(program, macroEnv)
+
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		io.WriteString(out, "Macro error:\n\t"+err.Error()+"\n")
+		return nil, false
+	}
+
+	return expanded, true
+}
+
+//resolve binds the identifiers of program. Undefined names are printed and
+//stop the program from running, shadowed names are only warned about.
+func resolve(out io.Writer, program ast.Node) bool {
+	r := resolver.New(evaluator.BuiltinNames()...)
+	r.Resolve(program)
+
+	for _, msg := range r.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning: "+msg)
+	}
+
+	if len(r.Errors()) != 0 {
+		io.WriteString(out, "Resolver errors:\n")
+		for _, msg := range r.Errors() {
+			io.WriteString(out, "\t"+msg+"\n")
+		}
+		return false
+	}
+
+	return true
 }
 
 func printParserErrors(out io.Writer, errors []string) {
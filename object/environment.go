@@ -1,19 +1,68 @@
 package object
 
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.ctx = outer.ctx
+	env.limits = outer.limits
+	return env
+}
+
+//NewFrameEnvironment returns the environment of a function call, with room for
+//the given number of slots. Calls deferred from within the function are
+//registered on it.
+func NewFrameEnvironment(outer *Environment, slots int) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.frame = true
+	if slots > 0 {
+		env.slots = make([]slot, slots)
+	}
 	return env
 }
 
+//NewEnvironment returns an empty environment. Its maps are made by the first
+//binding by name, as a function frame may only ever use slots.
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{}
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool
+	outer  *Environment
+	slots  []slot
+
+	frame    bool
+	deferred []DeferredCall
+
+	importer Importer
+	dir      string
+
+	ctx    context.Context
+	limits *Limits
+}
+
+//slot holds a local variable the resolver assigned an index to
+type slot struct {
+	value    Object
+	constant bool
+}
+
+//Importer loads the module an import expression evaluated in env refers to
+type Importer interface {
+	Import(name string, env *Environment) Object
+}
+
+//DeferredCall is a call registered by a defer statement
+type DeferredCall struct {
+	Fn   Object
+	Args []Object
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -24,7 +73,158 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+//GetLocal looks name up in this scope only
+func (e *Environment) GetLocal(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	return obj, ok
+}
+
+//Names returns the names bound in this scope, sorted
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Set binds name in this scope. It returns an *Error if name is a constant of this scope.
 func (e *Environment) Set(name string, val Object) Object {
+	if e.consts[name] {
+		return &Error{Message: fmt.Sprintf("cannot assign to constant %s", name)}
+	}
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+//SetConst binds name as a constant. It returns an *Error if name is already bound in this scope.
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts[name] {
+		return &Error{Message: fmt.Sprintf("cannot assign to constant %s", name)}
+	}
+	if _, ok := e.store[name]; ok {
+		return &Error{Message: fmt.Sprintf("cannot redeclare %s as constant", name)}
+	}
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.store[name] = val
+	e.consts[name] = true
+	return val
+}
+
+//SetSlot binds the variable name in the given slot of this scope, like Set
+//binds it by name. A variable bound in a slot is not found by Get.
+func (e *Environment) SetSlot(index int, name string, val Object) Object {
+	s := e.slot(index)
+	if s.constant {
+		return &Error{Message: fmt.Sprintf("cannot assign to constant %s", name)}
+	}
+	s.value = val
+	return val
+}
+
+//SetConstSlot binds the constant name in the given slot of this scope, like
+//SetConst binds it by name
+func (e *Environment) SetConstSlot(index int, name string, val Object) Object {
+	s := e.slot(index)
+	if s.constant {
+		return &Error{Message: fmt.Sprintf("cannot assign to constant %s", name)}
+	}
+	if s.value != nil {
+		return &Error{Message: fmt.Sprintf("cannot redeclare %s as constant", name)}
+	}
+	s.value = val
+	s.constant = true
+	return val
+}
+
+func (e *Environment) slot(index int) *slot {
+	for len(e.slots) <= index {
+		e.slots = append(e.slots, slot{})
+	}
+	return &e.slots[index]
+}
+
+//GetSlot returns the value in the given slot of the scope depth levels out.
+//It reports false if nothing is bound in that slot yet.
+func (e *Environment) GetSlot(depth, index int) (Object, bool) {
+	env := e
+	for i := 0; i < depth && env != nil; i++ {
+		env = env.outer
+	}
+	if env == nil || index >= len(env.slots) {
+		return nil, false
+	}
+
+	value := env.slots[index].value
+	return value, value != nil
+}
+
+//IsConst reports whether name is a constant of this scope
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
+}
+
+//Defer registers call on the nearest enclosing function frame. It returns
+//false if the environment is not inside a function call.
+func (e *Environment) Defer(call DeferredCall) bool {
+	for env := e; env != nil; env = env.outer {
+		if env.frame {
+			env.deferred = append(env.deferred, call)
+			return true
+		}
+	}
+	return false
+}
+
+//Deferred returns the calls deferred on this frame, in the order they were registered
+func (e *Environment) Deferred() []DeferredCall {
+	return e.deferred
+}
+
+//SetImporter makes imp load the imports evaluated in this environment and
+//the environments it encloses, resolving relative paths against dir
+func (e *Environment) SetImporter(imp Importer, dir string) {
+	e.importer = imp
+	e.dir = dir
+}
+
+//Importer returns the nearest importer and its directory, or nil if there is none
+func (e *Environment) Importer() (Importer, string) {
+	for env := e; env != nil; env = env.outer {
+		if env.importer != nil {
+			return env.importer, env.dir
+		}
+	}
+	return nil, ""
+}
+
+//SetContext makes ctx the context of evaluation in this environment and the
+//environments later enclosed by it
+func (e *Environment) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+//Context returns the context of evaluation in this environment, or nil if there is none
+func (e *Environment) Context() context.Context {
+	return e.ctx
+}
+
+//SetLimits makes limits bound evaluation in this environment and the
+//environments later enclosed by it
+func (e *Environment) SetLimits(limits *Limits) {
+	e.limits = limits
+}
+
+//Limits returns the limits of evaluation in this environment, or nil if there are none
+func (e *Environment) Limits() *Limits {
+	return e.limits
+}
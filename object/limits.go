@@ -0,0 +1,77 @@
+package object
+
+import "fmt"
+
+//Limits bounds the resources evaluation in an environment may use. A zero
+//field means no limit. Exceeding a limit returns an *Error with Limit set,
+//which scripts cannot catch.
+type Limits struct {
+	MaxDepth        int // function calls in progress at once
+	MaxSteps        int // nodes evaluated, over the lifetime of the Limits
+	MaxArrayLength  int
+	MaxStringLength int // in bytes
+	MaxHashSize     int // in pairs
+
+	depth int
+	steps int
+}
+
+const (
+	//DefaultDepth is a call depth well below the one that overflows the Go stack
+	DefaultDepth = 10000
+	//DefaultArrayLength is an array length well within the memory of a machine
+	DefaultArrayLength = 10000000
+)
+
+func limitError(limit string, max int) *Error {
+	return &Error{Message: fmt.Sprintf("limit exceeded: %s is %d", limit, max), Limit: limit}
+}
+
+//Enter records a function call, or returns an error if it is one too deep.
+//Every successful Enter must be followed by a Leave.
+func (l *Limits) Enter() *Error {
+	if l.MaxDepth > 0 && l.depth >= l.MaxDepth {
+		return limitError("MaxDepth", l.MaxDepth)
+	}
+	l.depth++
+	return nil
+}
+
+//Leave records the end of a function call
+func (l *Limits) Leave() {
+	l.depth--
+}
+
+//Step records the evaluation of a node, or returns an error if there have been too many
+func (l *Limits) Step() *Error {
+	if l.MaxSteps > 0 && l.steps >= l.MaxSteps {
+		return limitError("MaxSteps", l.MaxSteps)
+	}
+	l.steps++
+	return nil
+}
+
+//CheckLength returns an error if an array of length elements is too long
+func (l *Limits) CheckLength(length int64) *Error {
+	if l.MaxArrayLength > 0 && length > int64(l.MaxArrayLength) {
+		return limitError("MaxArrayLength", l.MaxArrayLength)
+	}
+	return nil
+}
+
+//CheckSize returns an error if obj is a string, array or hash larger than allowed
+func (l *Limits) CheckSize(obj Object) *Error {
+	switch obj := obj.(type) {
+	case *String:
+		if l.MaxStringLength > 0 && len(obj.Value) > l.MaxStringLength {
+			return limitError("MaxStringLength", l.MaxStringLength)
+		}
+	case *Array:
+		return l.CheckLength(int64(len(obj.Elements)))
+	case *Hash:
+		if l.MaxHashSize > 0 && len(obj.Pairs) > l.MaxHashSize {
+			return limitError("MaxHashSize", l.MaxHashSize)
+		}
+	}
+	return nil
+}
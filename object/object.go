@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"sort"
 	"strings"
 
 	"github.com/literallystan/go-terpreter/ast"
@@ -20,6 +22,10 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
+	MODULE_OBJ       = "MODULE"
 )
 
 //ObjectType string representation of the object's type
@@ -76,6 +82,28 @@ func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 //Error handles error messages to throw from the interpretor
 type Error struct {
 	Message string
+	Thrown  Object // the value of a throw statement, nil for runtime errors
+	Line    int    // position of the node that raised the error, 0 if unknown
+	Column  int
+	Stack   []Frame // calls the error propagated through, innermost first
+	Limit   string  // the field of Limits exceeded, such as MaxDepth; try does not catch such errors
+
+	Internal string // Go stack of the panic an internal error was recovered from
+}
+
+//Frame is a call of a function on the stack of an error
+type Frame struct {
+	Function  string // name the function was bound to, <anonymous> if none
+	Line      int    // position of the call
+	Column    int
+	DefLine   int // position of the function
+	DefColumn int
+}
+
+//String ...
+func (f Frame) String() string {
+	return fmt.Sprintf("in %s (defined at %d:%d) called at %d:%d",
+		f.Function, f.DefLine, f.DefColumn, f.Line, f.Column)
 }
 
 //Type returns the object's Type
@@ -84,10 +112,51 @@ func (e *Error) Type() ObjectType { return ERROR_OBJ }
 //Inspect returns the literal value as a string
 func (e *Error) Inspect() string { return "ERROR: " + e.Message }
 
+//Traceback returns Inspect followed by the position of the error and the
+//calls it propagated through, one per line, innermost first. Consecutive
+//identical calls are printed once.
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+	if e.Line != 0 {
+		fmt.Fprintf(&out, "\n\tat %d:%d", e.Line, e.Column)
+	}
+	for i := 0; i < len(e.Stack); {
+		frame := e.Stack[i]
+		out.WriteString("\n\t" + frame.String())
+
+		// recursion repeats the same call, printed once with its count
+		repeated := 0
+		for i++; i < len(e.Stack) && e.Stack[i] == frame; i++ {
+			repeated++
+		}
+		if repeated > 0 {
+			fmt.Fprintf(&out, "\n\t... repeated %d more times", repeated)
+		}
+	}
+	if e.Internal != "" {
+		out.WriteString("\n" + e.Internal)
+	}
+
+	return out.String()
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
+	ParamTypes []*ast.TypeAnnotation // nil if no parameter is annotated
+	ReturnType *ast.TypeAnnotation
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Doc        string
+	Name       string // name of the let or const the function was declared by, if any
+	Line       int    // position of the fn keyword
+	Column     int
+
+	Slots     int          // of the function's scope, see ast.FunctionLiteral
+	Self      *ast.Binding // slot of self, nil if the function does not use it
+	OuterSelf *ast.Binding
+	Receiver  Object // bound to self by a method call, nil for a plain call
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -95,20 +164,83 @@ func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	for i, p := range f.Parameters {
+		if i < len(f.ParamTypes) && f.ParamTypes[i] != nil {
+			params = append(params, p.String()+": "+f.ParamTypes[i].String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString("fn")
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") {\n")
+	out.WriteString(") ")
+	if f.ReturnType != nil {
+		out.WriteString("-> " + f.ReturnType.String() + " ")
+	}
+	out.WriteString("{\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
 
 	return out.String()
 }
 
+//Quote wraps an unevaluated AST node, as returned by quote()
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+//Macro is a macro literal together with the environment it was defined in
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+//Module is an imported file or standard module. Its exports are the
+//top-level bindings of Env whose names do not start with an underscore.
+type Module struct {
+	Name string
+	Path string // the resolved file, empty for standard modules
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(" + m.Name + ")" }
+
+//Export returns the exported binding name
+func (m *Module) Export(name string) (Object, bool) {
+	if strings.HasPrefix(name, "_") {
+		return nil, false
+	}
+	return m.Env.GetLocal(name)
+}
+
 type String struct {
 	Value string
 }
@@ -117,7 +249,16 @@ func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn  BuiltinFunction
+	Doc string
+
+	// Arity, if not zero, is the number of arguments Fn takes. A method call
+	// checks it before Fn is called, so its error does not count the receiver.
+	Arity int
+
+	// Check, if set, checks args against limits before Fn creates a value
+	// whose size does not follow from theirs, such as an array from a range
+	Check func(limits *Limits, args ...Object) *Error
 }
 
 type BuiltinFunction func(args ...Object) Object
@@ -145,6 +286,128 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+//Range is a lazy sequence of integers from Start towards End, moving by Step.
+//Its elements are only materialized by ToArray.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Exclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString(fmt.Sprintf("%d", r.Start))
+	if r.Exclusive {
+		out.WriteString("..<")
+	} else {
+		out.WriteString("..")
+	}
+	out.WriteString(fmt.Sprintf("%d", r.End))
+	if r.Step != 1 {
+		out.WriteString(fmt.Sprintf("..%d", r.Step))
+	}
+
+	return out.String()
+}
+
+//span returns the distance from Start to the farthest integer the range may
+//reach and the size of its step, both in uint64 so that ranges spanning the
+//whole of int64 do not overflow. ok is false if the range is empty.
+func (r *Range) span() (distance, step uint64, ok bool) {
+	switch {
+	case r.Step > 0 && (r.End > r.Start || r.End == r.Start && !r.Exclusive):
+		distance, step = uint64(r.End)-uint64(r.Start), uint64(r.Step)
+	case r.Step < 0 && (r.End < r.Start || r.End == r.Start && !r.Exclusive):
+		distance, step = uint64(r.Start)-uint64(r.End), -uint64(r.Step)
+	default:
+		return 0, 0, false
+	}
+
+	if r.Exclusive {
+		distance--
+	}
+	return distance, step, true
+}
+
+//Len returns the number of integers in the range, or math.MaxInt64 if there are more
+func (r *Range) Len() int64 {
+	distance, step, ok := r.span()
+	if !ok {
+		return 0
+	}
+
+	last := distance / step
+	if last >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(last) + 1
+}
+
+//At returns the i-th integer of the range, ok is false when i is out of bounds
+func (r *Range) At(i int64) (int64, bool) {
+	distance, step, ok := r.span()
+	if !ok || i < 0 || uint64(i) > distance/step {
+		return 0, false
+	}
+	// the integer is within the bounds, so the wrapping arithmetic is exact
+	return r.Start + i*r.Step, true
+}
+
+//Last returns the last integer of the range, ok is false when it is empty
+func (r *Range) Last() (int64, bool) {
+	distance, step, ok := r.span()
+	if !ok {
+		return 0, false
+	}
+
+	offset := distance / step * step
+	if r.Step < 0 {
+		return int64(uint64(r.Start) - offset), true
+	}
+	return int64(uint64(r.Start) + offset), true
+}
+
+//Contains reports whether v is one of the integers in the range
+func (r *Range) Contains(v int64) bool {
+	distance, step, ok := r.span()
+	if !ok {
+		return false
+	}
+
+	var offset uint64
+	switch {
+	case r.Step > 0 && v >= r.Start:
+		offset = uint64(v) - uint64(r.Start)
+	case r.Step < 0 && v <= r.Start:
+		offset = uint64(r.Start) - uint64(v)
+	default:
+		return false
+	}
+	return offset <= distance && offset%step == 0
+}
+
+//MaxRangeArray is the length of the longest range ToArray materializes,
+//whatever the limits: a longer one would exhaust memory
+const MaxRangeArray = 1 << 24
+
+//ToArray materializes the range into an Array, or returns an error if it is
+//longer than MaxRangeArray
+func (r *Range) ToArray() (*Array, *Error) {
+	length := r.Len()
+	if length > MaxRangeArray {
+		return nil, &Error{Message: fmt.Sprintf("range of %d integers is too long for an array, the most is %d",
+			length, MaxRangeArray)}
+	}
+	elements := make([]Object, length, length)
+	for i := int64(0); i < length; i++ {
+		elements[i] = &Integer{Value: r.Start + i*r.Step}
+	}
+	return &Array{Elements: elements}, nil
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -182,8 +445,53 @@ type HashPair struct {
 	Value Object
 }
 
+//Hash remembers the order its keys were first set in. Keys added to Pairs
+//directly, without Set, are ordered after those by their Inspect string.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Order []HashKey
+}
+
+//NewHash returns an empty Hash
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+//Set stores pair under key. A new key is ordered after the existing ones, an
+//existing key keeps its position.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Order = append(h.Order, key)
+	}
+	h.Pairs[key] = pair
+}
+
+//Ordered returns the pairs in insertion order
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	seen := make(map[HashKey]bool, len(h.Order))
+
+	for _, key := range h.Order {
+		if pair, ok := h.Pairs[key]; ok && !seen[key] {
+			pairs = append(pairs, pair)
+			seen[key] = true
+		}
+	}
+
+	if len(pairs) < len(h.Pairs) {
+		rest := []HashPair{}
+		for key, pair := range h.Pairs {
+			if !seen[key] {
+				rest = append(rest, pair)
+			}
+		}
+		sort.Slice(rest, func(i, j int) bool {
+			return rest[i].Key.Inspect() < rest[j].Key.Inspect()
+		})
+		pairs = append(pairs, rest...)
+	}
+
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -192,7 +500,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +23,259 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected []int64
+	}{
+		{&Range{Start: 1, End: 5, Step: 1}, []int64{1, 2, 3, 4, 5}},
+		{&Range{Start: 0, End: 5, Step: 1, Exclusive: true}, []int64{0, 1, 2, 3, 4}},
+		{&Range{Start: 1, End: 10, Step: 3}, []int64{1, 4, 7, 10}},
+		{&Range{Start: 1, End: 10, Step: 3, Exclusive: true}, []int64{1, 4, 7}},
+		{&Range{Start: 5, End: 1, Step: -2}, []int64{5, 3, 1}},
+		{&Range{Start: 5, End: 1, Step: 1}, []int64{}},
+		{&Range{Start: 3, End: 3, Step: 1, Exclusive: true}, []int64{}},
+		{&Range{Start: 0, End: math.MaxInt64, Step: math.MaxInt64}, []int64{0, math.MaxInt64}},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: math.MinInt64}, []int64{math.MaxInt64, -1}},
+		{&Range{Start: math.MinInt64, End: math.MaxInt64, Step: math.MaxInt64}, []int64{math.MinInt64, -1, math.MaxInt64 - 1}},
+		{&Range{Start: math.MaxInt64 - 1, End: math.MaxInt64, Step: 1}, []int64{math.MaxInt64 - 1, math.MaxInt64}},
+		{&Range{Start: math.MinInt64, End: math.MinInt64 + 1, Step: 1, Exclusive: true}, []int64{math.MinInt64}},
+	}
+
+	for _, tt := range tests {
+		if tt.r.Len() != int64(len(tt.expected)) {
+			t.Errorf("%s has wrong length. got=%d, want=%d",
+				tt.r.Inspect(), tt.r.Len(), len(tt.expected))
+			continue
+		}
+
+		arr, errObj := tt.r.ToArray()
+		if errObj != nil {
+			t.Fatalf("%s ToArray() returned error: %s", tt.r.Inspect(), errObj.Message)
+		}
+		for i, want := range tt.expected {
+			got, ok := tt.r.At(int64(i))
+			if !ok || got != want {
+				t.Errorf("%s At(%d) wrong. got=%d, want=%d", tt.r.Inspect(), i, got, want)
+			}
+			if !tt.r.Contains(want) {
+				t.Errorf("%s does not contain %d", tt.r.Inspect(), want)
+			}
+			if arr.Elements[i].(*Integer).Value != want {
+				t.Errorf("%s ToArray()[%d] wrong. got=%s", tt.r.Inspect(), i, arr.Elements[i].Inspect())
+			}
+		}
+
+		if tt.r.Contains(100) {
+			t.Errorf("%s contains 100", tt.r.Inspect())
+		}
+		last, ok := tt.r.Last()
+		if len(tt.expected) > 0 && (!ok || last != tt.expected[len(tt.expected)-1]) {
+			t.Errorf("%s Last() wrong. got=%d", tt.r.Inspect(), last)
+		}
+	}
+}
+
+func TestToArrayTooLong(t *testing.T) {
+	r := &Range{Start: 1, End: 100000000000, Step: 1}
+	arr, errObj := r.ToArray()
+	if arr != nil || errObj == nil {
+		t.Fatalf("ToArray() of %s did not fail", r.Inspect())
+	}
+	expected := "range of 100000000000 integers is too long for an array, the most is 16777216"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestLargeRange(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		len      int64
+		contains []int64
+		excludes []int64
+		last     int64
+	}{
+		{&Range{Start: 0, End: math.MaxInt64, Step: 1}, math.MaxInt64, []int64{0, 5, math.MaxInt64}, []int64{-1, math.MinInt64}, math.MaxInt64},
+		{&Range{Start: 0, End: math.MaxInt64, Step: 1, Exclusive: true}, math.MaxInt64, []int64{math.MaxInt64 - 1}, []int64{math.MaxInt64}, math.MaxInt64 - 1},
+		{&Range{Start: math.MinInt64, End: math.MaxInt64, Step: 1}, math.MaxInt64, []int64{math.MinInt64, 0, math.MaxInt64}, nil, math.MaxInt64},
+		{&Range{Start: 0, End: 10, Step: math.MaxInt64}, 1, []int64{0}, []int64{10, math.MaxInt64}, 0},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: -1}, math.MaxInt64, []int64{math.MaxInt64, 0, math.MinInt64}, nil, math.MinInt64},
+		{&Range{Start: 0, End: math.MinInt64, Step: math.MinInt64}, 2, []int64{0, math.MinInt64}, []int64{-1, 1}, math.MinInt64},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Len(); got != tt.len {
+			t.Errorf("%s has wrong length. got=%d, want=%d", tt.r.Inspect(), got, tt.len)
+		}
+		for _, v := range tt.contains {
+			if !tt.r.Contains(v) {
+				t.Errorf("%s does not contain %d", tt.r.Inspect(), v)
+			}
+		}
+		for _, v := range tt.excludes {
+			if tt.r.Contains(v) {
+				t.Errorf("%s contains %d", tt.r.Inspect(), v)
+			}
+		}
+		if last, ok := tt.r.Last(); !ok || last != tt.last {
+			t.Errorf("%s Last() wrong. got=%d, want=%d", tt.r.Inspect(), last, tt.last)
+		}
+	}
+}
+
+func TestEnvironmentConst(t *testing.T) {
+	env := NewEnvironment()
+	one := &Integer{Value: 1}
+
+	if result := env.SetConst("a", one); result != one {
+		t.Fatalf("SetConst returned %s", result.Inspect())
+	}
+	if !env.IsConst("a") {
+		t.Errorf("a is not constant")
+	}
+
+	if _, ok := env.Set("a", &Integer{Value: 2}).(*Error); !ok {
+		t.Errorf("rebinding a constant did not return an Error")
+	}
+	if val, _ := env.Get("a"); val != one {
+		t.Errorf("constant was rebound. got=%s", val.Inspect())
+	}
+
+	inner := NewEnclosedEnvironment(env)
+	if _, ok := inner.Set("a", &Integer{Value: 3}).(*Error); ok {
+		t.Errorf("shadowing a constant in an enclosed environment returned an Error")
+	}
+	if inner.IsConst("a") {
+		t.Errorf("a is constant in the enclosed environment")
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	hash := NewHash()
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		key := &String{Value: name}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+
+	key := &String{Value: "zeta"}
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 2}})
+
+	expected := "{zeta: 2, alpha: 1, mid: 1}"
+	if hash.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. want=%q, got=%q", expected, hash.Inspect())
+	}
+
+	unordered := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, name := range []string{"b", "c", "a"} {
+		key := &String{Value: name}
+		unordered.Pairs[key.HashKey()] = HashPair{Key: key, Value: &Integer{Value: 1}}
+	}
+
+	expected = "{a: 1, b: 1, c: 1}"
+	if unordered.Inspect() != expected {
+		t.Errorf("unordered.Inspect() wrong. want=%q, got=%q", expected, unordered.Inspect())
+	}
+}
+
+func TestEnvironmentSlots(t *testing.T) {
+	outer := NewFrameEnvironment(NewEnvironment(), 1)
+	outer.SetSlot(0, "a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+	inner.SetSlot(2, "b", &Integer{Value: 2})
+
+	tests := []struct {
+		depth, slot int
+		expected    int64 // 0 means no value
+	}{
+		{1, 0, 1},
+		{0, 2, 2},
+		{0, 0, 0},
+		{0, 5, 0},
+		{1, 1, 0},
+		{3, 0, 0},
+	}
+
+	for _, tt := range tests {
+		val, ok := inner.GetSlot(tt.depth, tt.slot)
+		if tt.expected == 0 {
+			if ok {
+				t.Errorf("GetSlot(%d, %d) found %v", tt.depth, tt.slot, val)
+			}
+			continue
+		}
+		if !ok || val.(*Integer).Value != tt.expected {
+			t.Errorf("GetSlot(%d, %d) wrong. got=%v, %t", tt.depth, tt.slot, val, ok)
+		}
+	}
+
+	if _, ok := inner.Get("b"); ok {
+		t.Errorf("variable bound in a slot was found by name")
+	}
+
+	errors := []struct {
+		result   Object
+		expected string
+	}{
+		{inner.SetConstSlot(2, "b", &Integer{Value: 3}), "cannot redeclare b as constant"},
+		{inner.SetConstSlot(3, "c", &Integer{Value: 3}), ""},
+		{inner.SetSlot(3, "c", &Integer{Value: 4}), "cannot assign to constant c"},
+		{inner.SetConstSlot(3, "c", &Integer{Value: 4}), "cannot assign to constant c"},
+	}
+
+	for _, tt := range errors {
+		errObj, isErr := tt.result.(*Error)
+		if tt.expected == "" {
+			if isErr {
+				t.Errorf("unexpected error: %s", errObj.Message)
+			}
+			continue
+		}
+		if !isErr || errObj.Message != tt.expected {
+			t.Errorf("wrong result. want error %q, got=%v", tt.expected, tt.result)
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	f := Frame{Function: "f", Line: 2, Column: 3, DefLine: 1, DefColumn: 9}
+	err := &Error{Message: "boom", Line: 1, Column: 20, Stack: []Frame{
+		f, f, f,
+		{Function: "<anonymous>", Line: 3, Column: 2, DefLine: 3, DefColumn: 1},
+	}}
+
+	expected := `ERROR: boom
+	at 1:20
+	in f (defined at 1:9) called at 2:3
+	... repeated 2 more times
+	in <anonymous> (defined at 3:1) called at 3:2`
+	if err.Traceback() != expected {
+		t.Errorf("wrong traceback. want=\n%s\ngot=\n%s", expected, err.Traceback())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := &Limits{MaxDepth: 2, MaxStringLength: 3}
+
+	if limits.Enter() != nil || limits.Enter() != nil {
+		t.Fatalf("calls within MaxDepth were refused")
+	}
+	if err := limits.Enter(); err == nil || err.Limit != "MaxDepth" {
+		t.Errorf("call beyond MaxDepth was not refused. got=%v", err)
+	}
+	limits.Leave()
+	if limits.Enter() != nil {
+		t.Errorf("call after Leave was refused")
+	}
+
+	if limits.CheckSize(&String{Value: "abc"}) != nil {
+		t.Errorf("string within MaxStringLength was refused")
+	}
+	if err := limits.CheckSize(&String{Value: "abcd"}); err == nil || err.Message != "limit exceeded: MaxStringLength is 3" {
+		t.Errorf("wrong error for long string. got=%v", err)
+	}
+	if limits.CheckSize(&Array{Elements: make([]Object, 1000)}) != nil {
+		t.Errorf("array was refused without MaxArrayLength")
+	}
+}
@@ -0,0 +1,371 @@
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Program rewrites program, in place, into one that evaluates to the same
+//result with less work:
+//
+//	integer, string and boolean expressions of literals are folded, 1 + 2 * 3 becoming 7
+//	if expressions whose condition is a literal are replaced by the branch taken
+//	uses of constants bound to an integer, string or boolean literal are replaced by the literal
+//
+//Expressions that fail at runtime, such as 1 / 0, are left for the evaluator to
+//report.
+func Program(program *ast.Program) *ast.Program {
+	o := &optimizer{scope: newScope(nil)}
+	program.Statements = o.statements(program.Statements)
+	return program
+}
+
+//scope maps names to the literal of a constant, or to nil for a name bound to
+//anything else, which hides constants of outer scopes
+type scope struct {
+	outer *scope
+	names map[string]ast.Expression
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, names: make(map[string]ast.Expression)}
+}
+
+func (s *scope) lookup(name string) ast.Expression {
+	for ; s != nil; s = s.outer {
+		if value, ok := s.names[name]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+//optimizer follows the environments the evaluator creates: one per function
+//call and catch block. A name is only replaced after its const statement, so a
+//use that fails at runtime because it comes first still fails.
+type optimizer struct {
+	scope *scope
+}
+
+//statements optimizes a program or block. A branch chosen by a constant
+//condition is spliced into it, as blocks do not have environments of their own.
+func (o *optimizer) statements(stmts []ast.Statement) []ast.Statement {
+	result := []ast.Statement{}
+
+	for _, stmt := range stmts {
+		stmt = o.statement(stmt)
+
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if block, ok := es.Expression.(*ast.BlockStatement); ok && len(block.Statements) > 0 {
+				result = append(result, block.Statements...)
+				continue
+			}
+		}
+		result = append(result, stmt)
+	}
+
+	return result
+}
+
+func (o *optimizer) statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value)
+		o.scope.names[stmt.Name.Value] = nil
+	case *ast.ConstStatement:
+		stmt.Value = o.expression(stmt.Value)
+		o.scope.names[stmt.Name.Value] = nil
+		if isInlinable(stmt.Value) {
+			o.scope.names[stmt.Name.Value] = stmt.Value
+		}
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.DeferStatement:
+		o.call(stmt.Call)
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression)
+	}
+	return stmt
+}
+
+func (o *optimizer) block(block *ast.BlockStatement) {
+	block.Statements = o.statements(block.Statements)
+}
+
+func (o *optimizer) expression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if value := o.scope.lookup(exp.Value); value != nil {
+			return literalAt(value, exp.Token)
+		}
+	case *ast.PrefixExpression:
+		exp.Right = o.expression(exp.Right)
+		return foldPrefix(exp)
+	case *ast.InfixExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Right = o.expression(exp.Right)
+		return foldInfix(exp)
+	case *ast.IfExpression:
+		return o.ifExpression(exp)
+	case *ast.BlockStatement:
+		o.block(exp)
+	case *ast.FunctionLiteral:
+		o.function(exp.Parameters, exp.Body)
+	case *ast.CallExpression:
+		o.call(exp)
+	case *ast.ArrayLiteral:
+		o.expressions(exp.Elements)
+	case *ast.HashLiteral:
+		for i := range exp.Pairs {
+			exp.Pairs[i].Key = o.expression(exp.Pairs[i].Key)
+			exp.Pairs[i].Value = o.expression(exp.Pairs[i].Value)
+		}
+	case *ast.IndexExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Index = o.expression(exp.Index)
+	case *ast.MemberExpression:
+		exp.Object = o.expression(exp.Object)
+	case *ast.RangeExpression:
+		exp.Start = o.expression(exp.Start)
+		exp.End = o.expression(exp.End)
+		if exp.Step != nil {
+			exp.Step = o.expression(exp.Step)
+		}
+	case *ast.TryExpression:
+		o.branch(exp.Block)
+		if exp.Catch != nil {
+			o.scope = newScope(o.scope)
+			o.scope.names[exp.CatchParam.Value] = nil
+			o.block(exp.Catch)
+			o.scope = o.scope.outer
+		}
+		if exp.Finally != nil {
+			o.block(exp.Finally)
+		}
+	}
+	return exp
+}
+
+//branch optimizes a block that may not run, or not to its end. Its lets and
+//consts bind in the enclosing environment if they run, so afterwards their
+//names hide outer constants but are not known to be constants themselves.
+func (o *optimizer) branch(block *ast.BlockStatement) {
+	o.scope = newScope(o.scope)
+	o.block(block)
+	names := o.scope.names
+	o.scope = o.scope.outer
+
+	for name := range names {
+		o.scope.names[name] = nil
+	}
+}
+
+func (o *optimizer) expressions(exps []ast.Expression) {
+	for i := range exps {
+		exps[i] = o.expression(exps[i])
+	}
+}
+
+func (o *optimizer) function(params []*ast.Identifier, body *ast.BlockStatement) {
+	o.scope = newScope(o.scope)
+	for _, param := range params {
+		o.scope.names[param.Value] = nil
+	}
+	o.block(body)
+	o.scope = o.scope.outer
+}
+
+//call leaves the argument of quote alone, as it is code and not a value
+func (o *optimizer) call(call *ast.CallExpression) {
+	if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+		return
+	}
+	call.Function = o.expression(call.Function)
+	o.expressions(call.Arguments)
+}
+
+//ifExpression replaces an if whose condition is a literal by the block of the
+//branch taken, when the block ends in an expression that gives it its value.
+//Otherwise the if is kept, to evaluate to null, without the other branch.
+func (o *optimizer) ifExpression(ie *ast.IfExpression) ast.Expression {
+	ie.Condition = o.expression(ie.Condition)
+
+	truthy, ok := isTruthy(ie.Condition)
+	if !ok {
+		o.branch(ie.Consequence)
+		if ie.Alternative != nil {
+			o.branch(ie.Alternative)
+		}
+		return ie
+	}
+
+	taken := ie.Alternative
+	if truthy {
+		taken = ie.Consequence
+	}
+	if taken == nil {
+		ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token}
+		return ie
+	}
+
+	o.block(taken)
+	n := len(taken.Statements)
+	if n == 0 {
+		return keepBranch(ie, taken)
+	}
+	es, ok := taken.Statements[n-1].(*ast.ExpressionStatement)
+	if !ok {
+		return keepBranch(ie, taken)
+	}
+	if n == 1 {
+		return es.Expression
+	}
+	return taken
+}
+
+//keepBranch makes ie always take the branch taken
+func keepBranch(ie *ast.IfExpression, taken *ast.BlockStatement) *ast.IfExpression {
+	ie.Condition = boolean(true, ie.Token)
+	ie.Consequence, ie.Alternative = taken, nil
+	return ie
+}
+
+//isTruthy reports whether a literal condition holds, and whether exp is one
+func isTruthy(exp ast.Expression) (truthy, ok bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+//isInlinable reports whether exp is a literal that can replace the name of a
+//constant. As values compare by value, it makes no difference that each use
+//evaluates to a new value.
+func isInlinable(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+//literalAt returns a copy of a literal positioned at tok
+func literalAt(lit ast.Expression, tok token.Token) ast.Expression {
+	switch lit := lit.(type) {
+	case *ast.IntegerLiteral:
+		return integer(lit.Value, tok)
+	case *ast.StringLiteral:
+		return str(lit.Value, tok)
+	case *ast.Boolean:
+		return boolean(lit.Value, tok)
+	}
+	return lit
+}
+
+func integer(value int64, at token.Token) *ast.IntegerLiteral {
+	tok := token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10), Line: at.Line, Column: at.Column}
+	return &ast.IntegerLiteral{Token: tok, Value: value}
+}
+
+func boolean(value bool, at token.Token) *ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Line: at.Line, Column: at.Column}
+	if value {
+		tok.Type, tok.Literal = token.TRUE, "true"
+	}
+	return &ast.Boolean{Token: tok, Value: value}
+}
+
+func str(value string, at token.Token) *ast.StringLiteral {
+	tok := token.Token{Type: token.STRING, Literal: value, Line: at.Line, Column: at.Column}
+	return &ast.StringLiteral{Token: tok, Value: value}
+}
+
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch pe.Operator {
+	case "!":
+		if truthy, ok := isTruthy(pe.Right); ok {
+			return boolean(!truthy, pe.Token)
+		}
+	case "-":
+		if right, ok := pe.Right.(*ast.IntegerLiteral); ok {
+			return integer(-right.Value, pe.Token)
+		}
+	}
+	return pe
+}
+
+//foldInfix folds the operations the evaluator performs on two integers, two
+//strings or two booleans, but for those that fail
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := ie.Right.(*ast.IntegerLiteral); ok {
+			return foldIntegers(ie, left, right)
+		}
+	case *ast.StringLiteral:
+		if right, ok := ie.Right.(*ast.StringLiteral); ok {
+			return foldStrings(ie, left, right)
+		}
+	case *ast.Boolean:
+		if right, ok := ie.Right.(*ast.Boolean); ok {
+			switch ie.Operator {
+			case "==":
+				return boolean(left.Value == right.Value, left.Token)
+			case "!=":
+				return boolean(left.Value != right.Value, left.Token)
+			}
+		}
+	}
+	return ie
+}
+
+func foldStrings(ie *ast.InfixExpression, left, right *ast.StringLiteral) ast.Expression {
+	l, r := left.Value, right.Value
+
+	switch ie.Operator {
+	case "+":
+		return str(l+r, left.Token)
+	case "<":
+		return boolean(l < r, left.Token)
+	case ">":
+		return boolean(l > r, left.Token)
+	case "==":
+		return boolean(l == r, left.Token)
+	case "!=":
+		return boolean(l != r, left.Token)
+	}
+	return ie
+}
+
+func foldIntegers(ie *ast.InfixExpression, left, right *ast.IntegerLiteral) ast.Expression {
+	l, r := left.Value, right.Value
+
+	switch ie.Operator {
+	case "+":
+		return integer(l+r, left.Token)
+	case "-":
+		return integer(l-r, left.Token)
+	case "*":
+		return integer(l*r, left.Token)
+	case "/":
+		if r != 0 {
+			return integer(l/r, left.Token)
+		}
+	case "<":
+		return boolean(l < r, left.Token)
+	case ">":
+		return boolean(l > r, left.Token)
+	case "==":
+		return boolean(l == r, left.Token)
+	case "!=":
+		return boolean(l != r, left.Token)
+	}
+	return ie
+}
@@ -0,0 +1,149 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/evaluator"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/object"
+	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/resolver"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	r := resolver.New(evaluator.BuiltinNames()...)
+	r.Resolve(program)
+	if len(r.Errors()) != 0 {
+		t.Fatalf("resolver errors for %q: %v", input, r.Errors())
+	}
+	return program
+}
+
+func TestProgram(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 2", "3"},
+		{"-(2 + 3)", "-5"},
+		{"9223372036854775807 + 1", "-9223372036854775808"},
+		{"1 < 2", "true"},
+		{"3 == 3", "true"},
+		{"3 != 3", "false"},
+		{"!5", "false"},
+		{"!!true", "true"},
+		{"true == false", "false"},
+		{`"a" + "b" + "c"`, `abc`},
+		{"1 / 0", "(1 / 0)"},
+		{"1 + true", "(1 + true)"},
+		{`"a" == "a"`, "true"},
+		{`"a" < "b"`, "true"},
+		{`"b" > "ab" == true`, "true"},
+		{"let x = 1; x + 1", "let x = 1;(x + 1)"},
+		{"const x = 2; x * 3", "const x = 2;6"},
+		{"const x = 1 + 1; const y = x * x; y", "const x = 2;const y = 4;4"},
+		{"const b = 1 > 2; !b", "const b = false;true"},
+		{`const s = "a"; s + "b"`, "const s = a;ab"},
+		{"const x = 1; fn(x) { x }", "const x = 1;fn(x) x"},
+		{"const x = 1; fn() { let x = 2; x }", "const x = 1;fn() let x = 2;x"},
+		{"const x = 1; fn() { x }", "const x = 1;fn() 1"},
+		{"const x = 1; try { x } catch (x) { x }", "const x = 1;try 1 catch(x) x"},
+		{"const x = 1; quote(x)", "const x = 1;quote(x)"},
+		{"const n = 1; let h = {}; h.n", "const n = 1;let h = {};(h.n)"},
+		{"if (true) { 1 } else { 2 }", "1"},
+		{"if (1 > 2) { 1 } else { 2 }", "2"},
+		{"if (false) { 1 }", "iffalse "},
+		{`if ("") { 1 }`, "1"},
+		{"let f = fn(x) { if (true) { let y = x; y } }", "let f = fn(x) let y = x;y;"},
+		{"let y = if (true) { let z = 1; z + 1 } else { 0 }", "let y = let z = 1;(z + 1);"},
+		{"if (g()) { 1 + 1 } else { 2 * 2 }", "ifg() 2else4"},
+		{"if (false) { 1 } else { let a = 1 }", "iftrue let a = 1;"},
+	}
+
+	for _, tt := range tests {
+		// g is defined so that the resolver accepts the last input
+		program := parse(t, "let g = fn() { true };"+tt.input)
+		program.Statements = program.Statements[1:]
+
+		Program(program)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+//TestBehaviorUnchanged evaluates each input as written and optimized
+func TestBehaviorUnchanged(t *testing.T) {
+	inputs := []string{
+		"1 + 2 * 3 - 4 / 2",
+		"-(1 - 5) * 2",
+		`"con" + "cat" + "enation"`,
+		`let s = "a"; s == s`,
+		`const s = "a"; s == s`,
+		`"a" == "a"`,
+		`"a" != "b"`,
+		`["a" < "b", "b" < "a", "a" > "a"]`,
+		`const greeting = "hi"; [greeting == "hi", greeting + "!"]`,
+		"true == !false",
+		"1 == true",
+		"if (true) { 1 }",
+		"if (false) { 1 }",
+		"if (false) { 1 } else { }",
+		"if (true) { } ; 5",
+		"let x = 5; if (true) { } ",
+		"let f = fn() { if (true) { let a = 1 } }; f()",
+		"if (false) { 1 } else { let b = 2 }",
+		"let f = fn() { if (true) { } }; [f()]",
+		"if (1 < 2) { 10 } else { 20 }",
+		"let f = fn() { if (true) { return 1 }; 2 }; f()",
+		"let f = fn() { if (true) { 1 } else { return 2 }; 3 }; f()",
+		"if (true) { let a = 1 }; a + 1",
+		"let r = if (true) { let z = 2; z * z }; [r, z]",
+		"const limit = 10 * 10; let f = fn(n) { n < limit }; [f(5), f(500)]",
+		"const x = 1; let f = fn(x) { x }; f(2) + x",
+		"const x = 1; let f = fn() { let x = 3; x }; f() + x",
+		"const x = 1; let f = fn(c) { if (c) { let x = 5; }; x }; [f(true), f(false)]",
+		`const x = 1; try { throw "e" } catch (x) { x["message"] }`,
+		`const on = true; if (on) { "on" } else { "off" }`,
+		"const n = 3; let h = {\"n\": n + 1}; h.n",
+		"const n = 3; quote(n + 1)",
+		"1 + true",
+		"-true",
+		"let x = 1 + 2; let y = x * (3 + 4); y",
+		"let total = fn(n) { if (n == 0) { 0 } else { n + total(n - 1) } }; total(10)",
+		"let a = [1 + 1, 2 * 2]; a[0 + 1]",
+		"(1 + 1)..(2 * 3)",
+		"9223372036854775807 + 1",
+		"let f = fn(c) { if (c) { const X = 1 }; X }; f(false)",
+		"let f = fn(c) { if (c) { const X = 1; X } else { 2 } }; [f(true), f(false)]",
+		"if (len([]) == 1) { const X = 1 }; X",
+		"if (len([]) == 1) { 0 } else { const X = 1 }; X",
+		"const X = 1; let f = fn(c) { if (c) { 0 } else { let X = 2 }; X }; [f(true), f(false)]",
+		`try { throw "e"; const X = 1 } catch (e) { 0 }; X`,
+		`try { const X = 1 } catch (e) { 0 }; X`,
+	}
+
+	for _, input := range inputs {
+		want := evaluate(parse(t, input))
+		got := evaluate(Program(parse(t, input)))
+		if got != want {
+			t.Errorf("optimizing changed the result of %q. want=%q, got=%q", input, want, got)
+		}
+	}
+}
+
+func evaluate(program *ast.Program) string {
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	if evaluated == nil {
+		return "<nil>"
+	}
+	return evaluated.Inspect()
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Associativity decides how a chain of the same infix operator is grouped
+type Associativity int
+
+const (
+	//LeftAssoc groups a - b - c as (a - b) - c
+	LeftAssoc Associativity = iota
+	//RightAssoc groups a ** b ** c as a ** (b ** c)
+	RightAssoc
+)
+
+//RegisterOperator makes the lexer of this parser emit a token of type t for
+//literal. The tokens the parser has already read ahead are read again, so
+//literal is recognized from the current token on.
+func (p *Parser) RegisterOperator(literal string, t token.TokenType) {
+	p.l.RegisterOperator(literal, t)
+	p.l.Rewind(p.curToken)
+	p.pushed = nil
+	p.curToken = p.l.NextToken()
+	p.peekToken = p.l.NextToken()
+}
+
+//RegisterPrefix makes fn parse expressions starting with tokens of type t on
+//this parser, replacing any existing prefix parse function for t
+func (p *Parser) RegisterPrefix(t token.TokenType, fn PrefixParseFn) {
+	p.registerPrefix(t, fn)
+}
+
+//RegisterInfix makes fn parse expressions with t as their operator on this
+//parser, binding with the given precedence. fn decides the associativity of t
+//by the precedence it parses the right operand with: precedence groups a chain
+//of t to the left, precedence-1 to the right.
+func (p *Parser) RegisterInfix(t token.TokenType, fn InfixParseFn, precedence int) {
+	p.registerInfix(t, fn)
+	p.precedences[t] = precedence
+	p.rightAssoc[t] = false
+}
+
+//RegisterPrefixOperator parses t as a prefix operator producing an ast.PrefixExpression
+func (p *Parser) RegisterPrefixOperator(t token.TokenType) {
+	p.RegisterPrefix(t, p.parsePrefixExpression)
+}
+
+//RegisterInfixOperator parses t as a binary operator producing an ast.InfixExpression
+func (p *Parser) RegisterInfixOperator(t token.TokenType, precedence int, assoc Associativity) {
+	p.RegisterInfix(t, p.parseInfixExpression, precedence)
+	p.rightAssoc[t] = assoc == RightAssoc
+}
+
+//CurToken returns the token being parsed
+func (p *Parser) CurToken() token.Token {
+	return p.curToken
+}
+
+//PeekToken returns the token after the current one
+func (p *Parser) PeekToken() token.Token {
+	return p.peekToken
+}
+
+//NextToken advances the parser by one token
+func (p *Parser) NextToken() {
+	p.nextToken()
+}
+
+//ExpectPeek advances if the next token is of type t, and records an error otherwise
+func (p *Parser) ExpectPeek(t token.TokenType) bool {
+	return p.expectPeek(t)
+}
+
+//ParseExpression parses the expression starting at the current token, stopping
+//at operators that bind no tighter than precedence
+func (p *Parser) ParseExpression(precedence int) ast.Expression {
+	return p.parseExpression(precedence)
+}
+
+//ParseExpressionList parses comma separated expressions up to the end token
+func (p *Parser) ParseExpressionList(end token.TokenType) []ast.Expression {
+	return p.parseExpressionList(end)
+}
+
+//Errorf records a parse error
+func (p *Parser) Errorf(format string, a ...interface{}) {
+	p.errors = append(p.errors, fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+const (
+	POW  token.TokenType = "**"
+	PIPE token.TokenType = "|>"
+	SQRT token.TokenType = "SQRT"
+)
+
+func newExtendedParser(input string) *Parser {
+	l := lexer.New(input)
+	l.RegisterOperator("**", POW)
+	l.RegisterOperator("|>", PIPE)
+	l.RegisterOperator("√", SQRT)
+
+	p := New(l)
+	p.RegisterInfixOperator(POW, PRODUCT+1, RightAssoc)
+	p.RegisterPrefixOperator(SQRT)
+
+	// x |> f parses as the call f(x)
+	p.RegisterInfix(PIPE, func(left ast.Expression) ast.Expression {
+		call := &ast.CallExpression{Token: p.CurToken(), Arguments: []ast.Expression{left}}
+		p.NextToken()
+		call.Function = p.ParseExpression(LOWEST + 1)
+		return call
+	}, LOWEST+1)
+
+	return p
+}
+
+func TestCustomOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"-a ** b", "((-a) ** b)"},
+		{"√a + b", "((√a) + b)"},
+		{"x |> f |> g", "g(f(x))"},
+		{"1 + 2 |> double", "double((1 + 2))"},
+		{"a - b - c", "((a - b) - c)"},
+	}
+
+	for _, tt := range tests {
+		p := newExtendedParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestRegisterOperatorAfterNew(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"√a ** b", "((√a) ** b)"},
+		{"// comment\n√a", "(√a)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.RegisterOperator("**", POW)
+		p.RegisterOperator("√", SQRT)
+		p.RegisterInfixOperator(POW, PRODUCT+1, RightAssoc)
+		p.RegisterPrefixOperator(SQRT)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		if strings.Contains(tt.input, "//") && len(l.Comments()) != 1 {
+			t.Errorf("comments read again. got=%v", l.Comments())
+		}
+	}
+}
+
+func TestCustomInfixAssociativity(t *testing.T) {
+	const CONS token.TokenType = "::"
+
+	l := lexer.New("a :: b :: c")
+	l.RegisterOperator("::", CONS)
+	p := New(l)
+
+	// a :: b parses as the call cons(a, b), grouping to the right
+	p.RegisterInfix(CONS, func(left ast.Expression) ast.Expression {
+		call := &ast.CallExpression{
+			Token:    p.CurToken(),
+			Function: &ast.Identifier{Value: "cons"},
+		}
+		p.NextToken()
+		call.Arguments = []ast.Expression{left, p.ParseExpression(SUM - 1)}
+		return call
+	}, SUM)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "cons(a, cons(b, c))" {
+		t.Errorf("wrong grouping. got=%q", program.String())
+	}
+}
+
+func TestCustomOperatorsArePerParser(t *testing.T) {
+	extended := newExtendedParser("a + b")
+	extended.RegisterInfixOperator(token.PLUS, PRODUCT+1, RightAssoc)
+
+	p := New(lexer.New("a + b * c + d"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "((a + (b * c)) + d)" {
+		t.Errorf("registering on one parser changed another. got=%q", program.String())
+	}
+}
+
+func TestErrorf(t *testing.T) {
+	p := New(lexer.New("1"))
+	p.Errorf("custom %s", "failure")
+
+	if len(p.Errors()) != 1 || p.Errors()[0] != "custom failure" {
+		t.Errorf("wrong errors. got=%q", p.Errors())
+	}
+}
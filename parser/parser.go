@@ -14,6 +14,7 @@ const (
 	LOWEST
 	EQUALS
 	LESSGREATER
+	RANGE
 	SUM
 	PRODUCT
 	PREFIX
@@ -21,22 +22,38 @@ const (
 	INDEX
 )
 
-var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+//defaultPrecedences is copied into every Parser, so registering an operator
+//on one parser does not affect the others
+var defaultPrecedences = map[token.TokenType]int{
+	token.EQ:         EQUALS,
+	token.NOT_EQ:     EQUALS,
+	token.LT:         LESSGREATER,
+	token.GT:         LESSGREATER,
+	token.RANGE:      RANGE,
+	token.RANGE_EXCL: RANGE,
+	token.PLUS:       SUM,
+	token.MINUS:      SUM,
+	token.SLASH:      PRODUCT,
+	token.ASTERISK:   PRODUCT,
+	token.LPAREN:     CALL,
+	token.LBRACKET:   INDEX,
+	token.DOT:        INDEX,
+}
+
+//Precedence returns the binding power of the infix operator t in a parser
+//without registered operators, or LOWEST if t is not an infix operator
+func Precedence(t token.TokenType) int {
+	if p, ok := defaultPrecedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 type (
-	prefixParseFn func() ast.Expression
-	infixParseFn  func(ast.Expression) ast.Expression
+	//PrefixParseFn parses an expression starting at the current token
+	PrefixParseFn func() ast.Expression
+	//InfixParseFn parses an expression whose operator is the current token
+	InfixParseFn func(ast.Expression) ast.Expression
 )
 
 //Parser ...
@@ -47,8 +64,26 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	//prevToken and pushed let backUp undo one nextToken: pushed, if set, is
+	//the token nextToken reads instead of asking the lexer
+	prevToken token.Token
+	pushed    *token.Token
+
+	//statementStart is the first token of the statement being parsed
+	statementStart token.Token
+
+	prefixParseFns map[token.TokenType]PrefixParseFn
+	infixParseFns  map[token.TokenType]InfixParseFn
+	precedences    map[token.TokenType]int
+	rightAssoc     map[token.TokenType]bool
+
+	//tolerant makes the parser keep unparseable code as ast.BadExpression and
+	//ast.BadStatement nodes instead of dropping it
+	tolerant bool
+
+	//scopes tracks the names bound in the program and each enclosing function body,
+	//mapped to whether they are constants
+	scopes []map[string]bool
 }
 
 //New ...
@@ -56,9 +91,16 @@ func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
 		errors: []string{},
+		scopes: []map[string]bool{{}},
 	}
 
-	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.precedences = make(map[token.TokenType]int)
+	for t, precedence := range defaultPrecedences {
+		p.precedences[t] = precedence
+	}
+	p.rightAssoc = make(map[token.TokenType]bool)
+
+	p.prefixParseFns = make(map[token.TokenType]PrefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
@@ -71,8 +113,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
 
-	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.infixParseFns = make(map[token.TokenType]InfixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
@@ -83,6 +128,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.RANGE, p.parseRangeExpression)
+	p.registerInfix(token.RANGE_EXCL, p.parseRangeExpression)
 	//Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
 	p.nextToken()
@@ -91,8 +139,26 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	if p.pushed != nil {
+		p.peekToken = *p.pushed
+		p.pushed = nil
+	} else {
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+//backUp undoes the last nextToken. It reports false if it cannot, because
+//the parser has not advanced since the last backUp.
+func (p *Parser) backUp() bool {
+	if p.pushed != nil {
+		return false
+	}
+	peek := p.peekToken
+	p.pushed = &peek
+	p.curToken, p.peekToken = p.prevToken, p.curToken
+	return true
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -145,15 +211,82 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+//SetTolerant switches fault-tolerant parsing on or off. In tolerant mode errors
+//are still reported, but the unparseable tokens are kept in the tree as
+//ast.BadExpression and ast.BadStatement nodes, so ParseProgram returns a
+//complete program with holes.
+func (p *Parser) SetTolerant(tolerant bool) {
+	p.tolerant = tolerant
+}
+
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+	p.statementStart = start
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if s := p.parseLetStatement(); s != nil {
+			stmt = s
+		}
+	case token.CONST:
+		if s := p.parseConstStatement(); s != nil {
+			stmt = s
+		}
+	case token.THROW:
+		stmt = p.parseThrowStatement()
+	case token.DEFER:
+		if s := p.parseDeferStatement(); s != nil {
+			stmt = s
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if stmt == nil && p.tolerant {
+		return p.badStatement(start)
+	}
+	return stmt
+}
+
+//badStatement skips to the end of a statement that failed to parse and
+//returns a placeholder spanning its tokens
+func (p *Parser) badStatement(from token.Token) *ast.BadStatement {
+	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) &&
+		!p.peekTokenIs(token.EOF) && !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
 	}
+
+	return &ast.BadStatement{From: from, To: p.curToken}
+}
+
+//missingExpression returns a placeholder for an expression expected at from,
+//the current token, in tolerant mode. If from ends the enclosing list, block
+//or statement, the parser backs up so that it is left for its parser.
+func (p *Parser) missingExpression(from token.Token) ast.Expression {
+	if !p.tolerant {
+		return nil
+	}
+
+	switch from.Type {
+	case token.RPAREN, token.RBRACKET, token.RBRACE, token.COMMA, token.SEMICOLON, token.EOF:
+		// a statement starting at from has to consume it to make progress
+		if from != p.statementStart && p.backUp() {
+			return &ast.BadExpression{From: from, To: from}
+		}
+	}
+	return p.badExpression(from)
+}
+
+//badExpression returns a placeholder for the tokens from from to the current
+//one in tolerant mode, and nil otherwise
+func (p *Parser) badExpression(from token.Token) ast.Expression {
+	if !p.tolerant {
+		return nil
+	}
+	return &ast.BadExpression{From: from, To: p.curToken}
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
@@ -165,6 +298,42 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		stmt.Type = p.parseTypeAnnotation()
+		if stmt.Type == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	p.attachDoc(stmt.Value, stmt.Token)
+	p.nameFunction(stmt.Value, stmt.Name)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	p.declare(stmt.Name.Value, false)
+
+	return stmt
+}
+
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	stmt := &ast.ConstStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -172,14 +341,59 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
+	p.attachDoc(stmt.Value, stmt.Token)
+	p.nameFunction(stmt.Value, stmt.Name)
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
+	p.declare(stmt.Name.Value, true)
+
 	return stmt
 }
 
+//attachDoc gives a function bound by a let or const the /// comment above the
+//statement, unless it already has one of its own
+func (p *Parser) attachDoc(value ast.Expression, start token.Token) {
+	if fn, ok := value.(*ast.FunctionLiteral); ok && fn.Doc == "" {
+		fn.Doc = p.l.DocBefore(start.Line)
+	}
+}
+
+//nameFunction gives a function bound by a let or const the name it is bound
+//to, for stack traces
+func (p *Parser) nameFunction(value ast.Expression, name *ast.Identifier) {
+	if fn, ok := value.(*ast.FunctionLiteral); ok && fn.Name == "" {
+		fn.Name = name.Value
+	}
+}
+
+func (p *Parser) openScope() {
+	p.scopes = append(p.scopes, map[string]bool{})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+//declare records a binding in the innermost scope, reporting rebound constants
+func (p *Parser) declare(name string, constant bool) {
+	scope := p.scopes[len(p.scopes)-1]
+	isConst, bound := scope[name]
+
+	switch {
+	case isConst:
+		msg := fmt.Sprintf("cannot assign to constant %s", name)
+		p.errors = append(p.errors, msg)
+	case bound && constant:
+		msg := fmt.Sprintf("cannot redeclare %s as constant", name)
+		p.errors = append(p.errors, msg)
+	default:
+		scope[name] = constant
+	}
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -194,6 +408,44 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	exp := p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		if exp != nil {
+			msg := fmt.Sprintf("expected function call after defer, got %s", exp.String())
+			p.errors = append(p.errors, msg)
+		}
+		return nil
+	}
+	stmt.Call = call
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -207,12 +459,17 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	start := p.curToken
+
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return p.missingExpression(start)
 	}
 	leftExp := prefix()
+	if leftExp == nil {
+		return p.badExpression(start)
+	}
 
 	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
@@ -223,12 +480,15 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		p.nextToken()
 
 		leftExp = infix(leftExp)
+		if leftExp == nil {
+			return p.badExpression(start)
+		}
 	}
 	return leftExp
 }
 
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+	if p, ok := p.precedences[p.peekToken.Type]; ok {
 		return p
 	}
 
@@ -236,7 +496,7 @@ func (p *Parser) peekPrecedence() int {
 }
 
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
+	if p, ok := p.precedences[p.curToken.Type]; ok {
 		return p
 	}
 
@@ -282,6 +542,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.rightAssoc[p.curToken.Type] {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -331,6 +594,56 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		p.openScope()
+		p.declare(expression.CatchParam.Value, false)
+		expression.Catch = p.parseBlockStatement()
+		p.closeScope()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errors = append(p.errors, "expected catch or finally after try block")
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
@@ -348,13 +661,27 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
-	lit := &ast.FunctionLiteral{Token: p.curToken}
+	lit := &ast.FunctionLiteral{Token: p.curToken, Doc: p.l.DocBefore(p.curToken.Line)}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	p.openScope()
+	defer p.closeScope()
+
+	lit.Parameters, lit.ParamTypes = p.parseFunctionParameters()
+	for _, param := range lit.Parameters {
+		p.declare(param.Value, false)
+	}
+
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		lit.ReturnType = p.parseTypeAnnotation()
+		if lit.ReturnType == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -365,31 +692,103 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.openScope()
+	defer p.closeScope()
+
+	var types []*ast.TypeAnnotation
+	lit.Parameters, types = p.parseFunctionParameters()
+	if types != nil {
+		p.errors = append(p.errors, "macro parameters cannot have types")
+	}
+	for _, param := range lit.Parameters {
+		p.declare(param.Value, false)
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+//parseFunctionParameters parses a parameter list and the types given to its
+//parameters. The types are nil if there are none.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []*ast.TypeAnnotation) {
 	identifiers := []*ast.Identifier{}
+	types := []*ast.TypeAnnotation{}
+	annotated := false
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil
 	}
 
-	p.nextToken()
+	parseParam := func() bool {
+		p.nextToken()
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		identifiers = append(identifiers, ident)
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		var typ *ast.TypeAnnotation
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if typ = p.parseTypeAnnotation(); typ == nil {
+				return false
+			}
+			annotated = true
+		}
+		types = append(types, typ)
+		return true
+	}
+
+	if !parseParam() {
+		return nil, nil
+	}
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		if !parseParam() {
+			return nil, nil
+		}
 	}
 
 	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !annotated {
+		types = nil
+	}
+	return identifiers, types
+}
+
+//parseTypeAnnotation parses the type name after the current : or -> token
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	// fn is a keyword, every other type name is an identifier
+	if p.peekTokenIs(token.FUNCTION) {
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	return identifiers
+	typ := &ast.TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+	for _, name := range ast.TypeNames {
+		if name == typ.Name {
+			return typ
+		}
+	}
+
+	msg := fmt.Sprintf("unknown type %s", typ.Name)
+	p.errors = append(p.errors, msg)
+	return nil
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -402,11 +801,11 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
-func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn PrefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
 
-func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+func (p *Parser) registerInfix(tokenType token.TokenType, fn InfixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
@@ -414,6 +813,18 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseImportExpression() ast.Expression {
+	exp := &ast.ImportExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	exp.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -439,7 +850,8 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(end) {
+	// in tolerant mode the elements parsed are kept even if the list is not closed
+	if !p.expectPeek(end) && !p.tolerant {
 		return nil
 	}
 
@@ -459,9 +871,40 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+func (p *Parser) parseRangeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.RangeExpression{
+		Token:     p.curToken,
+		Start:     left,
+		Exclusive: p.curTokenIs(token.RANGE_EXCL),
+	}
+
+	p.nextToken()
+	exp.End = p.parseExpression(RANGE)
+
+	if p.peekTokenIs(token.RANGE) {
+		p.nextToken()
+		p.nextToken()
+		exp.Step = p.parseExpression(RANGE)
+	}
+
+	return exp
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashPair{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -474,7 +917,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -42,6 +42,65 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	input := "const limit = 10;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ConstStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ConstStatement. got=%T", program.Statements[0])
+	}
+	if stmt.TokenLiteral() != "const" {
+		t.Fatalf("stmt.TokenLiteral not 'const'. got=%q", stmt.TokenLiteral())
+	}
+	if !testIdentifier(t, stmt.Name, "limit") {
+		return
+	}
+	testIntegerLiteral(t, stmt.Value, 10)
+}
+
+func TestConstViolations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"const a = 1; let a = 2;", []string{"cannot assign to constant a"}},
+		{"const a = 1; const a = 2;", []string{"cannot assign to constant a"}},
+		{"let a = 1; const a = 2;", []string{"cannot redeclare a as constant"}},
+		{"fn(a) { const a = 1; }", []string{"cannot redeclare a as constant"}},
+		{"const a = 1; if (true) { let a = 2; }", []string{"cannot assign to constant a"}},
+		{"const a = 1; fn() { let a = 2; }", []string{}},
+		{"const a = 1; fn(a) { a }", []string{}},
+		{"let a = 1; let a = 2;", []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. got=%q, want=%q", tt.input, errors, tt.expected)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if errors[i] != msg {
+				t.Errorf("%q: wrong error. got=%q, want=%q", tt.input, errors[i], msg)
+			}
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -350,6 +409,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a * b.c.d",
+			"(a * ((b.c).d))",
+		},
+		{
+			"0..<n + 1",
+			"(0..<(n + 1))",
+		},
+		{
+			"a == 1..10..2 * b",
+			"(a == (1..10..(2 * b)))",
+		},
+		{
+			"-a.b(c)[0]",
+			"(-((a.b)(c)[0]))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -509,6 +584,103 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParam string
+		hasFinally    bool
+	}{
+		{`try { x } catch (e) { y }`, "e", false},
+		{`try { x } finally { y }`, "", true},
+		{`try { x } catch (err) { y } finally { z }`, "err", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+		}
+
+		if len(exp.Block.Statements) != 1 {
+			t.Errorf("try block is not 1 statements. got=%d", len(exp.Block.Statements))
+		}
+
+		if tt.expectedParam == "" {
+			if exp.Catch != nil {
+				t.Errorf("exp.Catch was not nil. got=%+v", exp.Catch)
+			}
+		} else if !testIdentifier(t, exp.CatchParam, tt.expectedParam) {
+			return
+		}
+
+		if (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("exp.Finally wrong. got=%+v", exp.Finally)
+		}
+	}
+}
+
+func TestTryWithoutHandlers(t *testing.T) {
+	l := lexer.New(`try { x }`)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 || errors[0] != "expected catch or finally after try block" {
+		t.Errorf("wrong parser errors. got=%q", errors)
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	l := lexer.New(`throw "boom";`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Value.String() != "boom" {
+		t.Errorf("stmt.Value wrong. got=%q", stmt.Value.String())
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	l := lexer.New(`defer close(file, 1);`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.DeferStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.DeferStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Call.Function, "close") {
+		return
+	}
+
+	if len(stmt.Call.Arguments) != 2 {
+		t.Fatalf("wrong length of arguments. got=%d", len(stmt.Call.Arguments))
+	}
+
+	l = lexer.New(`defer 1 + 2;`)
+	p = New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 || errors[0] != "expected function call after defer, got (1 + 2)" {
+		t.Errorf("wrong parser errors. got=%q", errors)
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -556,6 +728,125 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionDocComments(t *testing.T) {
+	input := `/// adds x and y
+let add = fn(x, y) { x + y };
+
+let plain = fn() { 1 };
+/// a constant
+/// function
+const one = fn() { 1 };
+/// not a function
+let two = 2;
+apply(
+  /// inline
+  fn(a) { a }
+);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	functions := []*ast.FunctionLiteral{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok {
+			functions = append(functions, fn)
+		}
+		return true
+	})
+
+	expected := []string{"adds x and y", "", "a constant\nfunction", "inline"}
+	if len(functions) != len(expected) {
+		t.Fatalf("wrong number of function literals. want=%d, got=%d",
+			len(expected), len(functions))
+	}
+
+	for i, fn := range functions {
+		if fn.Doc != expected[i] {
+			t.Errorf("functions[%d].Doc wrong. want=%q, got=%q", i, expected[i], fn.Doc)
+		}
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	input := `let add = fn(x, y) { x + y };
+const one = fn() { 1 };
+let two = 2;
+apply(fn(a) { a });`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	names := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok {
+			names = append(names, fn.Name)
+		}
+		return true
+	})
+
+	expected := []string{"add", "one", ""}
+	if len(names) != len(expected) {
+		t.Fatalf("wrong number of function literals. want=%d, got=%d",
+			len(expected), len(names))
+	}
+	for i, name := range names {
+		if name != expected[i] {
+			t.Errorf("functions[%d].Name wrong. want=%q, got=%q", i, expected[i], name)
+		}
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -586,6 +877,87 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input      string
+		params     []string
+		returnType string
+		str        string
+	}{
+		{"fn(x) { x }", nil, "", "fn(x) x"},
+		{"fn(name: string, count: int) -> array { [] }", []string{"string", "int"}, "array",
+			"fn(name: string, count: int) -> array []"},
+		{"fn(a, f: fn) { f(a) }", []string{"", "fn"}, "", "fn(a, f: fn) f(a)"},
+		{"fn() -> null { }", nil, "null", "fn() -> null "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+		if tt.params == nil && function.ParamTypes != nil {
+			t.Errorf("%q: ParamTypes not nil. got=%v", tt.input, function.ParamTypes)
+		}
+		for i, name := range tt.params {
+			typ := function.ParamType(i)
+			if name == "" && typ != nil || name != "" && (typ == nil || typ.Name != name) {
+				t.Errorf("%q: parameter %d has wrong type. want=%q, got=%v", tt.input, i, name, typ)
+			}
+		}
+
+		got := ""
+		if function.ReturnType != nil {
+			got = function.ReturnType.Name
+		}
+		if got != tt.returnType {
+			t.Errorf("%q: wrong return type. want=%q, got=%q", tt.input, tt.returnType, got)
+		}
+
+		if program.String() != tt.str {
+			t.Errorf("wrong String(). want=%q, got=%q", tt.str, program.String())
+		}
+	}
+
+	l := lexer.New("let x: int = 5;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	if stmt.Type == nil || stmt.Type.Name != "int" {
+		t.Errorf("let statement has wrong type. got=%v", stmt.Type)
+	}
+	if program.String() != "let x: int = 5;" {
+		t.Errorf("wrong String(). got=%q", program.String())
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: integer = 5;", "unknown type integer"},
+		{"fn(x: 5) { x }", "expected next token to be IDENT, got INT instead"},
+		{"fn(x) -> { x }", "expected next token to be IDENT, got { instead"},
+		{"macro(x: int) { x }", "macro parameters cannot have types"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -789,6 +1161,25 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestImportExpression(t *testing.T) {
+	input := `let strings = import "lib/strings";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	exp, ok := stmt.Value.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.ImportExpression. got=%T", stmt.Value)
+	}
+
+	if exp.Path.Value != "lib/strings" {
+		t.Errorf("exp.Path.Value not %q. got=%q", "lib/strings", exp.Path.Value)
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -835,6 +1226,81 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "person.greet(1)"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	member, ok := call.Function.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("call.Function not *ast.MemberExpression. got=%T", call.Function)
+	}
+
+	if !testIdentifier(t, member.Object, "person") {
+		return
+	}
+
+	if !testIdentifier(t, member.Property, "greet") {
+		return
+	}
+
+	if len(call.Arguments) != 1 {
+		t.Fatalf("wrong length of arguments. got=%d", len(call.Arguments))
+	}
+	testIntegerLiteral(t, call.Arguments[0], 1)
+}
+
+func TestParsingRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input     string
+		start     interface{}
+		end       interface{}
+		step      interface{}
+		exclusive bool
+	}{
+		{"1..10", 1, 10, nil, false},
+		{"0..<n", 0, "n", nil, true},
+		{"1..10..2", 1, 10, 2, false},
+		{"a..<b..c", "a", "b", "c", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		rangeExp, ok := stmt.Expression.(*ast.RangeExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.RangeExpression. got=%T", stmt.Expression)
+		}
+
+		if rangeExp.Exclusive != tt.exclusive {
+			t.Errorf("rangeExp.Exclusive not %t. got=%t", tt.exclusive, rangeExp.Exclusive)
+		}
+		testLiteralExpression(t, rangeExp.Start, tt.start)
+		testLiteralExpression(t, rangeExp.End, tt.end)
+
+		if tt.step == nil {
+			if rangeExp.Step != nil {
+				t.Errorf("rangeExp.Step not nil. got=%s", rangeExp.Step)
+			}
+			continue
+		}
+		testLiteralExpression(t, rangeExp.Step, tt.step)
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -853,21 +1319,27 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	expected := map[string]int64{
-		"one":   1,
-		"two":   2,
-		"three": 3,
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
 		}
 
-		expectedValue := expected[literal.String()]
+		if literal.String() != expected[i].key {
+			t.Errorf("pair %d has wrong key. want=%q, got=%q", i, expected[i].key, literal.String())
+		}
 
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expected[i].value)
 	}
 }
 
@@ -920,10 +1392,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -933,6 +1405,143 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+
+	if hash.String() != `{one:(0 + 1), two:(10 - 8), three:(15 / 5)}` {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
+
+func TestTolerantMode(t *testing.T) {
+	input := `let = 5;
+let x = 5 + ;
+let f = fn(a) { let = 1; a };
+x`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.SetTolerant(true)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("tolerant mode did not report errors")
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d (%s)",
+			len(program.Statements), program.String())
+	}
+
+	bad, ok := program.Statements[0].(*ast.BadStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.BadStatement. got=%T", program.Statements[0])
+	}
+	if bad.From.Literal != "let" || bad.To.Literal != ";" || bad.To.Line != 1 {
+		t.Errorf("bad statement has wrong span. got=%+v..%+v", bad.From, bad.To)
+	}
+
+	let, ok := program.Statements[1].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.LetStatement. got=%T", program.Statements[1])
+	}
+	infix, ok := let.Value.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("let.Value is not ast.InfixExpression. got=%T", let.Value)
+	}
+	badExp, ok := infix.Right.(*ast.BadExpression)
+	if !ok {
+		t.Fatalf("infix.Right is not ast.BadExpression. got=%T", infix.Right)
+	}
+	if badExp.From.Literal != ";" || badExp.From.Line != 2 {
+		t.Errorf("bad expression has wrong span. got=%+v..%+v", badExp.From, badExp.To)
+	}
+
+	fnLet := program.Statements[2].(*ast.LetStatement)
+	body := fnLet.Value.(*ast.FunctionLiteral).Body
+	if len(body.Statements) != 2 {
+		t.Fatalf("function body does not contain 2 statements. got=%d", len(body.Statements))
+	}
+	if _, ok := body.Statements[0].(*ast.BadStatement); !ok {
+		t.Errorf("body.Statements[0] is not ast.BadStatement. got=%T", body.Statements[0])
+	}
+	testIdentifier(t, body.Statements[1].(*ast.ExpressionStatement).Expression, "a")
+
+	testIdentifier(t, program.Statements[3].(*ast.ExpressionStatement).Expression, "x")
+}
+
+func TestTolerantModeKeepsStructure(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"foo(1, ;", []string{"foo(1, <bad expression>)"}},
+		{"[1, 2;", []string{"[1, 2]"}},
+		{"[1, , 2]", []string{"[1, <bad expression>, 2]"}},
+		{"let f = fn(x) { x + }; let w = 1;", []string{"let f = fn(x) (x + <bad expression>);", "let w = 1;"}},
+		{"if (x) { foo(1, } else { 2 }; 3", []string{"ifx foo(1, <bad expression>)else2", "3"}},
+		{"let x = ; x", []string{"let x = <bad expression>;", "x"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.SetTolerant(true)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: no errors reported", tt.input)
+		}
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("%q: wrong number of statements. want=%d, got=%d (%q)",
+				tt.input, len(tt.expected), len(program.Statements), program.String())
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("%q: statement %d wrong. want=%q, got=%q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestTolerantModeNeverProducesNilNodes(t *testing.T) {
+	inputs := []string{
+		"if (x { y }",
+		"let f = fn(x, { x };",
+		"add(1, 2",
+		"[1, 2",
+		"{1: 2, 3}",
+		"a[1",
+		"a.",
+		"const = 1;",
+		"defer 1;",
+		"try { 1 }",
+		"import 5",
+		")",
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		p.SetTolerant(true)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: no errors reported", input)
+		}
+
+		for i, stmt := range program.Statements {
+			if stmt == nil {
+				t.Errorf("%q: program.Statements[%d] is nil", input, i)
+			}
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%q: program.String() panicked: %v", input, r)
+				}
+			}()
+			_ = program.String()
+		}()
 	}
 }
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/literallystan/go-terpreter/evaluator"
 	"github.com/literallystan/go-terpreter/lexer"
 	"github.com/literallystan/go-terpreter/object"
 	"github.com/literallystan/go-terpreter/parser"
+	"github.com/literallystan/go-terpreter/resolver"
 )
 
 const PROMPT = ">> "
@@ -17,6 +19,11 @@ const PROMPT = ">> "
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	env.SetImporter(evaluator.NewLoader(evaluator.SearchPath()), ".")
+	env.SetLimits(&object.Limits{MaxDepth: object.DefaultDepth, MaxArrayLength: object.DefaultArrayLength})
+	macroEnv := object.NewEnvironment()
+	macroEnv.SetLimits(env.Limits())
+	docLines := ""
 
 	for {
 		fmt.Printf(PROMPT)
@@ -27,6 +34,12 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "///") {
+			// keep doc comments for the declaration on the next line
+			docLines += line + "\n"
+			continue
+		}
+		line, docLines = docLines+line, ""
 		l := lexer.New(line)
 
 		p := parser.New(l)
@@ -37,14 +50,56 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			io.WriteString(out, "\t"+err.Error()+"\n")
+			continue
+		}
+
+		// a name may be defined on a later line, so it is only missing if
+		// evaluation does not find it
+		r := resolver.New(append(evaluator.BuiltinNames(), env.Names()...)...)
+		r.SetOpen(true)
+		r.Resolve(expanded)
+		for _, msg := range r.Warnings() {
+			io.WriteString(out, "\twarning: "+msg+"\n")
+		}
+		if len(r.Errors()) != 0 {
+			printParserErrors(out, r.Errors())
+			continue
+		}
+
+		evaluated := evaluator.Eval(expanded, env)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, errObj.Traceback())
+			io.WriteString(out, "\n")
+		} else if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
+			printDoc(out, evaluated)
 		}
 	}
 }
 
+//printDoc writes the doc comment of a function or builtin below its value
+func printDoc(out io.Writer, obj object.Object) {
+	var doc string
+	switch fn := obj.(type) {
+	case *object.Function:
+		doc = fn.Doc
+	case *object.Builtin:
+		doc = fn.Doc
+	}
+
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		io.WriteString(out, "/// "+line+"\n")
+	}
+}
+
 func printParserErrors(out io.Writer, errors []string) {
 	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
@@ -0,0 +1,302 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/literallystan/go-terpreter/ast"
+)
+
+//Resolver binds the identifiers of a program to the variables they refer to.
+//Every function call and catch block gets a scope of its own at runtime, so
+//a local variable is found Depth scopes out, in a numbered slot. Variables of
+//the global scope keep being looked up by name.
+//
+//Declarations are hoisted to the top of their scope. A local variable is only
+//stored in its slot, so a name used before its let has run is looked up by
+//name at runtime, and finds the global of that name if there is one.
+//
+//A function that uses self gets a slot for it, which a method call binds to
+//the receiver and any other call to the self of the enclosing function.
+type Resolver struct {
+	predeclared map[string]bool
+	scope       *scope
+
+	//open makes names declared nowhere globals that may be defined later
+	open bool
+
+	bindings map[*ast.Identifier]*ast.Binding
+	errors   []string
+	warnings []string
+}
+
+type scope struct {
+	outer  *scope
+	slots  map[string]int
+	global bool
+}
+
+func (s *scope) lookup(name string) (slot int, ok bool) {
+	slot, ok = s.slots[name]
+	return slot, ok
+}
+
+//New returns a Resolver for programs that can use the predeclared names, such
+//as builtins or the globals of a REPL session, without declaring them
+func New(predeclared ...string) *Resolver {
+	r := &Resolver{
+		predeclared: make(map[string]bool),
+		bindings:    make(map[*ast.Identifier]*ast.Binding),
+	}
+	for _, name := range predeclared {
+		r.predeclared[name] = true
+	}
+	return r
+}
+
+//SetOpen switches open resolution on or off. An open resolver takes a name
+//declared nowhere to be a global that may be defined later, as in a REPL
+//session where a function can use a global of a later line. The name is
+//looked up by name at runtime and its uses are warnings instead of errors.
+func (r *Resolver) SetOpen(open bool) {
+	r.open = open
+}
+
+//Errors returns the uses of undefined names
+func (r *Resolver) Errors() []string {
+	return r.errors
+}
+
+//Warnings returns the declarations that shadow a name of an outer scope, and
+//the uses of undefined names in an open resolver
+func (r *Resolver) Warnings() []string {
+	return r.warnings
+}
+
+//Resolve sets the Binding of every identifier of node that refers to a local
+//variable. node is resolved as a program, in the global scope.
+func (r *Resolver) Resolve(node ast.Node) {
+	r.scope = &scope{slots: make(map[string]int), global: true}
+	r.hoist(node)
+	ast.Inspect(node, r.visit)
+	r.scope = nil
+}
+
+func (r *Resolver) openScope() {
+	r.scope = &scope{outer: r.scope, slots: make(map[string]int)}
+}
+
+func (r *Resolver) closeScope() {
+	r.scope = r.scope.outer
+}
+
+//hoist declares the lets and consts of node in the current scope, without
+//descending into the scopes nested in it
+func (r *Resolver) hoist(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			r.declare(node.Name)
+		case *ast.ConstStatement:
+			r.declare(node.Name)
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.TryExpression:
+			r.hoist(node.Block)
+			if node.Finally != nil {
+				r.hoist(node.Finally)
+			}
+			return false
+		case *ast.CallExpression:
+			return !isCallTo(node, "quote")
+		}
+		return true
+	})
+}
+
+//declare adds ident to the current scope, once
+func (r *Resolver) declare(ident *ast.Identifier) {
+	if _, ok := r.scope.lookup(ident.Value); ok {
+		return
+	}
+
+	if !r.scope.global {
+		for outer := r.scope.outer; outer != nil; outer = outer.outer {
+			if _, ok := outer.lookup(ident.Value); ok {
+				r.warn(ident, "%s shadows a declaration in an outer scope", ident.Value)
+				break
+			}
+			if outer.global && r.predeclared[ident.Value] {
+				r.warn(ident, "%s shadows a predeclared name", ident.Value)
+			}
+		}
+	}
+
+	r.scope.slots[ident.Value] = len(r.scope.slots)
+}
+
+//bind records where ident is found. An identifier reached twice, e.g. because
+//a macro put it in two places, is left to be looked up by name.
+func (r *Resolver) bind(ident *ast.Identifier, binding *ast.Binding) {
+	if prev, ok := r.bindings[ident]; ok {
+		if prev == nil || binding == nil || *prev != *binding {
+			binding = nil
+		}
+	}
+	r.bindings[ident] = binding
+	ident.Binding = binding
+}
+
+//binding finds the declaration of name seen from the current scope. The
+//binding is nil for a global.
+func (r *Resolver) binding(name string) (*ast.Binding, bool) {
+	depth := 0
+	for s := r.scope; s != nil; s = s.outer {
+		if slot, ok := s.lookup(name); ok {
+			if s.global {
+				return nil, true
+			}
+			return &ast.Binding{Depth: depth, Slot: slot}, true
+		}
+		depth++
+	}
+	return nil, false
+}
+
+//bindDeclaration binds an identifier that has been declared in the current scope
+func (r *Resolver) bindDeclaration(ident *ast.Identifier) {
+	binding, _ := r.binding(ident.Value)
+	r.bind(ident, binding)
+}
+
+func (r *Resolver) reference(ident *ast.Identifier) {
+	binding, ok := r.binding(ident.Value)
+	r.bind(ident, binding)
+
+	// self is bound when a function is called as a method
+	if ok || r.predeclared[ident.Value] || ident.Value == "self" {
+		return
+	}
+	if r.open {
+		r.warn(ident, "identifier not found: %s", ident.Value)
+	} else {
+		r.error(ident, "identifier not found: %s", ident.Value)
+	}
+}
+
+func (r *Resolver) visit(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		r.bindDeclaration(node.Name)
+		ast.Inspect(node.Value, r.visit)
+		return false
+	case *ast.ConstStatement:
+		r.bindDeclaration(node.Name)
+		ast.Inspect(node.Value, r.visit)
+		return false
+	case *ast.Identifier:
+		r.reference(node)
+	case *ast.MemberExpression:
+		ast.Inspect(node.Object, r.visit)
+		return false
+	case *ast.FunctionLiteral:
+		outerSelf, _ := r.binding("self")
+		scope := r.function(node.Parameters, node.Body, usesSelf(node.Body))
+		node.Slots = len(scope.slots)
+		if slot, ok := scope.lookup("self"); ok {
+			node.Self = &ast.Binding{Slot: slot}
+			node.OuterSelf = outerSelf
+		}
+		return false
+	case *ast.MacroLiteral:
+		r.function(node.Parameters, node.Body, false)
+		return false
+	case *ast.TryExpression:
+		r.try(node)
+		return false
+	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			r.quoted(node)
+			return false
+		}
+	}
+	return true
+}
+
+//function resolves a function in a scope of its own, which it returns. With
+//self, self gets a slot unless a parameter already has the name.
+func (r *Resolver) function(params []*ast.Identifier, body *ast.BlockStatement, self bool) *scope {
+	r.openScope()
+	defer r.closeScope()
+
+	for _, param := range params {
+		r.declare(param)
+		r.bindDeclaration(param)
+	}
+	if _, ok := r.scope.lookup("self"); self && !ok {
+		r.scope.slots["self"] = len(r.scope.slots)
+	}
+	r.hoist(body)
+	ast.Inspect(body, r.visit)
+	return r.scope
+}
+
+//usesSelf reports whether self appears in body, including in the functions
+//nested in it
+func usesSelf(body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == "self" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func (r *Resolver) try(node *ast.TryExpression) {
+	ast.Inspect(node.Block, r.visit)
+
+	if node.Catch != nil {
+		r.openScope()
+		r.declare(node.CatchParam)
+		r.bindDeclaration(node.CatchParam)
+		r.hoist(node.Catch)
+		ast.Inspect(node.Catch, r.visit)
+		r.closeScope()
+	}
+
+	if node.Finally != nil {
+		ast.Inspect(node.Finally, r.visit)
+	}
+}
+
+//quoted resolves the unquote calls of a quote call. The rest of its argument
+//is code that is not evaluated where it appears.
+func (r *Resolver) quoted(node *ast.CallExpression) {
+	for _, arg := range node.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpression); ok && isCallTo(call, "unquote") {
+				for _, arg := range call.Arguments {
+					ast.Inspect(arg, r.visit)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+func (r *Resolver) error(ident *ast.Identifier, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", ident.Token.Line, ident.Token.Column) + fmt.Sprintf(format, a...)
+	r.errors = append(r.errors, msg)
+}
+
+func (r *Resolver) warn(ident *ast.Identifier, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", ident.Token.Line, ident.Token.Column) + fmt.Sprintf(format, a...)
+	r.warnings = append(r.warnings, msg)
+}
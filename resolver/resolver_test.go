@@ -0,0 +1,204 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+//bindings lists the identifiers of program as name@line:column=depth/slot,
+//or name@line:column=global when they are looked up by name
+func bindings(program ast.Node) []string {
+	list := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		where := "global"
+		if ident.Binding != nil {
+			where = string(rune('0'+ident.Binding.Depth)) + "/" + string(rune('0'+ident.Binding.Slot))
+		}
+		list = append(list, ident.Value+"="+where)
+		return true
+	})
+	return list
+}
+
+func TestResolveBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let x = 1; x",
+			[]string{"x=global", "x=global"},
+		},
+		{
+			"let f = fn(a, b) { let c = a; b + c }",
+			[]string{"f=global", "a=0/0", "b=0/1", "c=0/2", "a=0/0", "b=0/1", "c=0/2"},
+		},
+		{
+			"let f = fn(a) { fn(b) { a + b + f(b) } }",
+			[]string{"f=global", "a=0/0", "b=0/0", "a=1/0", "b=0/0", "f=global", "b=0/0"},
+		},
+		{
+			"let f = fn() { if (true) { let y = 1 } y }",
+			[]string{"f=global", "y=0/0", "y=0/0"},
+		},
+		{
+			"let f = fn() { x; let x = 1 }",
+			[]string{"f=global", "x=0/0", "x=0/0"},
+		},
+		{
+			"let f = fn(a) { try { a } catch (e) { let m = e; a } }",
+			[]string{"f=global", "a=0/0", "a=0/0", "e=0/0", "m=0/1", "e=0/0", "a=1/0"},
+		},
+		{
+			"let f = fn(h) { h.len + len(h) }",
+			[]string{"f=global", "h=0/0", "h=0/0", "len=global", "len=global", "h=0/0"},
+		},
+		{
+			"let f = fn(a) { quote(a + unquote(a)) }",
+			[]string{"f=global", "a=0/0", "quote=global", "a=global", "unquote=global", "a=0/0"},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		r := New("len")
+		r.Resolve(program)
+
+		if len(r.Errors()) != 0 {
+			t.Errorf("unexpected errors for %q: %v", tt.input, r.Errors())
+		}
+
+		got := bindings(program)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong bindings for %q.\nwant=%v\ngot=%v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string
+		warnings []string
+	}{
+		{"x", []string{"1:1: identifier not found: x"}, nil},
+		{"let f = fn() { y + len([]) };\nf(z)", []string{"1:16: identifier not found: y", "2:3: identifier not found: z"}, nil},
+		{"let f = fn() { g() }; let g = fn() { 1 }", nil, nil},
+		{"let f = fn() { self.x }", nil, nil},
+		{"let a = 1; let f = fn(a) { a }", nil, []string{"1:23: a shadows a declaration in an outer scope"}},
+		{"let f = fn(a) { fn() { let a = 2; a } }", nil, []string{"1:28: a shadows a declaration in an outer scope"}},
+		{"let f = fn(len) { len }", nil, []string{"1:12: len shadows a predeclared name"}},
+		{"let a = 1; let a = 2; let f = fn(b) { let b = 1; b }", nil, nil},
+	}
+
+	for _, tt := range tests {
+		r := New("len")
+		r.Resolve(parse(t, tt.input))
+
+		if len(r.Errors()) != len(tt.errors) || len(r.Errors()) > 0 && !reflect.DeepEqual(r.Errors(), tt.errors) {
+			t.Errorf("wrong errors for %q.\nwant=%v\ngot=%v", tt.input, tt.errors, r.Errors())
+		}
+		if len(r.Warnings()) != len(tt.warnings) || len(r.Warnings()) > 0 && !reflect.DeepEqual(r.Warnings(), tt.warnings) {
+			t.Errorf("wrong warnings for %q.\nwant=%v\ngot=%v", tt.input, tt.warnings, r.Warnings())
+		}
+	}
+}
+
+func TestResolveOpen(t *testing.T) {
+	program := parse(t, "let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }")
+
+	r := New()
+	r.SetOpen(true)
+	r.Resolve(program)
+
+	if len(r.Errors()) != 0 {
+		t.Errorf("open resolver reported errors: %v", r.Errors())
+	}
+	if !reflect.DeepEqual(r.Warnings(), []string{"1:48: identifier not found: odd"}) {
+		t.Errorf("wrong warnings. got=%v", r.Warnings())
+	}
+
+	want := []string{"even=global", "n=0/0", "n=0/0", "odd=global", "n=0/0"}
+	if got := bindings(program); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong bindings.\nwant=%v\ngot=%v", want, got)
+	}
+}
+
+func TestResolveFunctionSlots(t *testing.T) {
+	tests := []struct {
+		input     string
+		slots     []int // of the functions, outermost first
+		self      []*ast.Binding
+		outerSelf []*ast.Binding
+	}{
+		{
+			"fn(a, b) { let c = a; c }",
+			[]int{3},
+			[]*ast.Binding{nil},
+			[]*ast.Binding{nil},
+		},
+		{
+			"fn(a) { let b = self; fn() { self } }",
+			[]int{3, 1},
+			[]*ast.Binding{{Slot: 1}, {Slot: 0}},
+			[]*ast.Binding{nil, {Depth: 0, Slot: 1}},
+		},
+		{
+			"fn() { try { 1 } catch (e) { fn(x) { self } } }",
+			[]int{1, 2},
+			[]*ast.Binding{{Slot: 0}, {Slot: 1}},
+			[]*ast.Binding{nil, {Depth: 1, Slot: 0}},
+		},
+		{
+			"fn(self) { self }",
+			[]int{1},
+			[]*ast.Binding{{Slot: 0}},
+			[]*ast.Binding{nil},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		New().Resolve(program)
+
+		functions := []*ast.FunctionLiteral{}
+		ast.Inspect(program, func(node ast.Node) bool {
+			if fn, ok := node.(*ast.FunctionLiteral); ok {
+				functions = append(functions, fn)
+			}
+			return true
+		})
+
+		if len(functions) != len(tt.slots) {
+			t.Fatalf("wrong number of functions in %q. got=%d", tt.input, len(functions))
+		}
+		for i, fn := range functions {
+			if fn.Slots != tt.slots[i] {
+				t.Errorf("wrong slots of function %d in %q. want=%d, got=%d", i, tt.input, tt.slots[i], fn.Slots)
+			}
+			if !reflect.DeepEqual(fn.Self, tt.self[i]) {
+				t.Errorf("wrong self of function %d in %q. want=%v, got=%v", i, tt.input, tt.self[i], fn.Self)
+			}
+			if !reflect.DeepEqual(fn.OuterSelf, tt.outerSelf[i]) {
+				t.Errorf("wrong outer self of function %d in %q. want=%v, got=%v", i, tt.input, tt.outerSelf[i], fn.OuterSelf)
+			}
+		}
+	}
+}
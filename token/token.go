@@ -7,6 +7,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the token's first character, 0 if unknown
+	Column  int // 1-based column of the token's first character
 }
 
 const (
@@ -16,6 +18,8 @@ const (
 	IDENT  = "IDENT" // add, foobar, x, y, ...
 	INT    = "INT"
 	STRING = "STRING"
+	// Comments are not returned by the lexer, see Lexer.Comments
+	COMMENT = "COMMENT"
 	// 1343456
 	// Operators
 	ASSIGN   = "="
@@ -29,6 +33,10 @@ const (
 	GT     = ">"
 	EQ     = "=="
 	NOT_EQ = "!="
+
+	RANGE      = ".."
+	RANGE_EXCL = "..<"
+	ARROW      = "->"
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -39,24 +47,41 @@ const (
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	COLON     = ":"
+	DOT       = "."
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MACRO    = "MACRO"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	DEFER    = "DEFER"
+	IMPORT   = "IMPORT"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"const":   CONST,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"macro":   MACRO,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"defer":   DEFER,
+	"import":  IMPORT,
 }
 
 //LookupIdent - check if a token is a keyword, if not just return IDENT
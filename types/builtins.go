@@ -0,0 +1,88 @@
+package types
+
+//generic is the level of the variables of a type scheme, which are replaced
+//by fresh variables each time the scheme is used
+const generic = 1 << 30
+
+func genericVar(id int) *Var {
+	return &Var{id: id, level: generic}
+}
+
+func sig(ret Type, params ...Type) *Func {
+	return &Func{Params: params, Return: ret}
+}
+
+var (
+	a = genericVar(-1)
+	b = genericVar(-2)
+)
+
+//builtins are the signatures of the builtin functions. A call must match one
+//of them.
+var builtins = map[string][]*Func{
+	"len": {
+		sig(Int, &Array{Elem: a}),
+		sig(Int, String),
+		sig(Int, Range),
+	},
+	"first": {
+		sig(a, &Array{Elem: a}),
+		sig(Int, Range),
+	},
+	"last": {
+		sig(a, &Array{Elem: a}),
+		sig(Int, Range),
+	},
+	"tail": {
+		sig(&Array{Elem: a}, &Array{Elem: a}),
+		sig(Range, Range),
+	},
+	"push": {
+		sig(&Array{Elem: a}, &Array{Elem: a}, a),
+		// arrays may hold values of different types
+		sig(&Array{Elem: Any}, &Array{Elem: a}, b),
+	},
+	"contains": {
+		sig(Bool, &Array{Elem: a}, b),
+		sig(Bool, String, String),
+		sig(Bool, &Hash{Key: a, Value: b}, Any),
+		sig(Bool, Range, Any),
+	},
+	"array": {
+		sig(&Array{Elem: Int}, Range),
+		sig(&Array{Elem: a}, &Array{Elem: a}),
+	},
+	"doc": {
+		sig(String, Any),
+	},
+	"print": {
+		{Params: []Type{Any}, Return: Null, Variadic: true},
+	},
+}
+
+//methods are the methods of the builtin types, by the name of the receiver's
+//type. Their signatures are those of builtins, with the receiver first.
+var methods = map[string]map[string][]*Func{
+	"string": {
+		"len":      builtins["len"],
+		"contains": builtins["contains"],
+		"upper":    {sig(String, String)},
+		"lower":    {sig(String, String)},
+	},
+	"array": {
+		"len":      builtins["len"],
+		"first":    builtins["first"],
+		"last":     builtins["last"],
+		"tail":     builtins["tail"],
+		"push":     builtins["push"],
+		"contains": builtins["contains"],
+	},
+	"range": {
+		"len":      builtins["len"],
+		"first":    builtins["first"],
+		"last":     builtins["last"],
+		"tail":     builtins["tail"],
+		"contains": builtins["contains"],
+		"array":    builtins["array"],
+	},
+}
@@ -0,0 +1,808 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/token"
+)
+
+//Checker infers the types of a program, Hindley-Milner style, and reports the
+//operations that would fail at runtime because of the types of their operands.
+//
+//Functions bound by let are generalized, so a function such as fn(x) { x } can
+//be called with values of any type. Code the checker cannot type precisely,
+//such as an array holding both integers and strings, gets the type any, which
+//is compatible with every type and never causes an error.
+type Checker struct {
+	scope   *scope
+	level   int
+	nextID  int
+	returns []*[]Type // the types returned by the functions being checked
+
+	trying bool
+	trail  []undo
+
+	types  map[ast.Expression]Type
+	errors []string
+}
+
+type scope struct {
+	outer *scope
+	names map[string]Type
+}
+
+//undo restores a variable changed while trying to unify two types
+type undo struct {
+	v        *Var
+	instance Type
+	level    int
+}
+
+//New returns a Checker that knows the signatures of the builtins
+func New() *Checker {
+	return &Checker{types: make(map[ast.Expression]Type)}
+}
+
+//Errors returns the type errors found, prefixed with their line and column
+func (c *Checker) Errors() []string {
+	return c.errors
+}
+
+//TypeOf returns the type inferred for exp, or any if exp has not been checked
+func (c *Checker) TypeOf(exp ast.Expression) Type {
+	if t, ok := c.types[exp]; ok {
+		return prune(t)
+	}
+	return Any
+}
+
+//Check infers the types of node, which is checked as a program
+func (c *Checker) Check(node ast.Node) {
+	c.scope = &scope{names: make(map[string]Type)}
+	for name, alts := range builtins {
+		c.scope.names[name] = &Overloaded{Name: name, Alts: alts}
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		c.statements(node.Statements)
+	case ast.Statement:
+		c.statement(node)
+	case ast.Expression:
+		c.expr(node)
+	}
+}
+
+func (c *Checker) errorf(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", tok.Line, tok.Column) + fmt.Sprintf(format, a...)
+	c.errors = append(c.errors, msg)
+}
+
+func (c *Checker) openScope() {
+	c.scope = &scope{outer: c.scope, names: make(map[string]Type)}
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.outer
+}
+
+func (c *Checker) lookup(name string) Type {
+	for s := c.scope; s != nil; s = s.outer {
+		if t, ok := s.names[name]; ok {
+			return c.instantiate(t)
+		}
+	}
+	// left to the resolver, or bound at runtime like self
+	return Any
+}
+
+func (c *Checker) newVar() *Var {
+	c.nextID++
+	return &Var{id: c.nextID, level: c.level}
+}
+
+//statements returns the type of the last statement, which is the value of a block
+func (c *Checker) statements(stmts []ast.Statement) Type {
+	result := Type(Null)
+	for _, stmt := range stmts {
+		result = c.statement(stmt)
+	}
+	return result
+}
+
+func (c *Checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt.Name, stmt.Type, stmt.Value)
+		return Null
+	case *ast.ConstStatement:
+		c.let(stmt.Name, nil, stmt.Value)
+		return Null
+	case *ast.ReturnStatement:
+		t := c.expr(stmt.ReturnValue)
+		if n := len(c.returns); n > 0 {
+			*c.returns[n-1] = append(*c.returns[n-1], t)
+		}
+		return t
+	case *ast.ThrowStatement:
+		c.expr(stmt.Value)
+		return Any
+	case *ast.DeferStatement:
+		c.expr(stmt.Call)
+		return Null
+	case *ast.ExpressionStatement:
+		return c.expr(stmt.Expression)
+	}
+	return Any
+}
+
+//let binds name to the generalized type of value, which must agree with typ
+//if it is not nil. A function can call itself by name, with the type it is
+//being given.
+func (c *Checker) let(name *ast.Identifier, typ *ast.TypeAnnotation, value ast.Expression) {
+	c.level++
+
+	var self *Var
+	if _, ok := value.(*ast.FunctionLiteral); ok {
+		self = c.newVar()
+		c.scope.names[name.Value] = self
+	}
+
+	t := c.expr(value)
+	if self != nil {
+		c.unify(self, t)
+	}
+	if typ != nil && !c.unify(t, c.annotation(typ)) {
+		c.errorf(typ.Token, "value of %s must be %s, got %s", name.Value, typ.Name, t)
+	}
+
+	c.level--
+	c.scope.names[name.Value] = c.generalize(t)
+	c.types[name] = t
+}
+
+func (c *Checker) block(block *ast.BlockStatement) Type {
+	return c.statements(block.Statements)
+}
+
+func (c *Checker) expr(exp ast.Expression) Type {
+	t := c.infer(exp)
+	c.types[exp] = t
+	return t
+}
+
+func (c *Checker) infer(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.Boolean:
+		return Bool
+	case *ast.StringLiteral:
+		return String
+	case *ast.Identifier:
+		return c.lookup(exp.Value)
+	case *ast.PrefixExpression:
+		return c.prefix(exp)
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.expr(exp.Condition)
+		t := c.block(exp.Consequence)
+		if exp.Alternative == nil {
+			return c.join(t, Null)
+		}
+		return c.join(t, c.block(exp.Alternative))
+	case *ast.FunctionLiteral:
+		return c.function(exp)
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.ArrayLiteral:
+		elem := Type(c.newVar())
+		for _, el := range exp.Elements {
+			elem = c.join(elem, c.expr(el))
+		}
+		return &Array{Elem: elem}
+	case *ast.HashLiteral:
+		return c.hash(exp)
+	case *ast.IndexExpression:
+		return c.index(exp)
+	case *ast.MemberExpression:
+		return c.member(exp, false)
+	case *ast.RangeExpression:
+		for _, bound := range []ast.Expression{exp.Start, exp.End, exp.Step} {
+			if bound == nil {
+				continue
+			}
+			if t := c.expr(bound); !c.unify(t, Int) {
+				c.errorf(exp.Token, "range bound must be int, got %s", t)
+			}
+		}
+		return Range
+	case *ast.TryExpression:
+		return c.try(exp)
+	}
+	// imports, macros and unparsed code
+	return Any
+}
+
+func (c *Checker) prefix(pe *ast.PrefixExpression) Type {
+	right := c.expr(pe.Right)
+
+	switch pe.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if !c.unify(right, Int) {
+			c.errorf(pe.Token, "unknown operator: -%s", right)
+		}
+		return Int
+	}
+	return Any
+}
+
+func (c *Checker) infix(ie *ast.InfixExpression) Type {
+	left := c.expr(ie.Left)
+	right := c.expr(ie.Right)
+
+	switch ie.Operator {
+	case "==", "!=":
+		return Bool
+	case "+":
+		want := Type(Int)
+		switch {
+		case prune(left) == String || prune(right) == String:
+			want = String
+		case prune(left) == Int || prune(right) == Int:
+		case !isConcrete(left) && !isConcrete(right):
+			// either ints or strings, as long as both are the same
+			c.unify(left, right)
+			return left
+		}
+		c.operands(ie, left, right, want)
+		return want
+	case "-", "*", "/":
+		c.operands(ie, left, right, Int)
+		return Int
+	case "<", ">":
+		want := Type(Int)
+		_, leftArray := prune(left).(*Array)
+		_, rightArray := prune(right).(*Array)
+		switch {
+		case prune(left) == String || prune(right) == String:
+			want = String
+		case leftArray || rightArray:
+			// arrays are ordered by their elements, whose order is checked at runtime
+			want = &Array{Elem: c.newVar()}
+		}
+		c.operands(ie, left, right, want)
+		return Bool
+	}
+	// operators registered on the parser
+	return Any
+}
+
+//operands unifies both operands of ie with want, and reports the error the
+//evaluator would raise if they cannot be
+func (c *Checker) operands(ie *ast.InfixExpression, left, right, want Type) {
+	ok := c.attempt(func() bool {
+		return c.unifyTypes(left, want) && c.unifyTypes(right, want)
+	})
+	if ok {
+		return
+	}
+
+	names := Strings(left, right)
+	if isConcrete(left) && isConcrete(right) && !c.compatible(left, right) {
+		c.errorf(ie.Token, "type mismatch: %s %s %s", names[0], ie.Operator, names[1])
+		return
+	}
+	c.errorf(ie.Token, "unknown operator: %s %s %s", names[0], ie.Operator, names[1])
+}
+
+//isConcrete reports whether t is known to be of some type
+func isConcrete(t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return false
+	case *Basic:
+		return t != Any
+	}
+	return true
+}
+
+//isHashable reports whether t can be the type of a hash key
+func isHashable(t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return true
+	case *Basic:
+		return t == Int || t == Bool || t == String || t == Any
+	}
+	return false
+}
+
+//function infers the type of a function from the types of what it returns.
+//The parameters are not generalized: every use in the body must agree.
+func (c *Checker) function(fl *ast.FunctionLiteral) Type {
+	c.openScope()
+	defer c.closeScope()
+
+	params := []Type{}
+	for i, param := range fl.Parameters {
+		t := Type(c.newVar())
+		if typ := fl.ParamType(i); typ != nil {
+			t = c.annotation(typ)
+		}
+		params = append(params, t)
+		c.scope.names[param.Value] = t
+		c.types[param] = t
+	}
+
+	returns := []Type{}
+	c.returns = append(c.returns, &returns)
+	result := c.block(fl.Body)
+	c.returns = c.returns[:len(c.returns)-1]
+
+	for _, t := range returns {
+		result = c.join(result, t)
+	}
+
+	if fl.ReturnType != nil {
+		want := c.annotation(fl.ReturnType)
+		if !c.unify(result, want) {
+			c.errorf(fl.ReturnType.Token, "return value must be %s, got %s", fl.ReturnType.Name, result)
+		}
+		result = want
+	}
+
+	return &Func{Params: params, Return: result}
+}
+
+//annotation returns the type named by typ. The parameters and results of a
+//function annotated fn are not known.
+func (c *Checker) annotation(typ *ast.TypeAnnotation) Type {
+	switch typ.Name {
+	case "int":
+		return Int
+	case "bool":
+		return Bool
+	case "string":
+		return String
+	case "null":
+		return Null
+	case "range":
+		return Range
+	case "array":
+		return &Array{Elem: c.newVar()}
+	case "hash":
+		return &Hash{Key: c.newVar(), Value: c.newVar()}
+	}
+	return Any
+}
+
+func (c *Checker) call(call *ast.CallExpression) Type {
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if ident.Value == "quote" || ident.Value == "unquote" {
+			return Any
+		}
+	}
+
+	var callee Type
+	if member, ok := call.Function.(*ast.MemberExpression); ok {
+		callee = c.member(member, true)
+	} else {
+		callee = c.expr(call.Function)
+	}
+
+	args := []Type{}
+	for _, arg := range call.Arguments {
+		args = append(args, c.expr(arg))
+	}
+
+	switch fn := prune(callee).(type) {
+	case *Overloaded:
+		return c.overloaded(call, fn, args)
+	case *Func:
+		return c.apply(call, fn, args)
+	case *Var:
+		ret := c.newVar()
+		c.unify(fn, &Func{Params: args, Return: ret})
+		return ret
+	}
+
+	if isConcrete(callee) {
+		c.errorf(call.Token, "not a function: %s", callee)
+	}
+	return Any
+}
+
+//apply checks the arguments of a call of a function. Extra arguments are
+//ignored, as they are by the evaluator.
+func (c *Checker) apply(call *ast.CallExpression, fn *Func, args []Type) Type {
+	if len(args) < len(fn.Params) {
+		c.errorf(call.Token, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.Params))
+		return fn.Return
+	}
+
+	for i, param := range fn.Params {
+		if !c.unify(param, args[i]) {
+			names := Strings(args[i], param)
+			c.errorf(call.Token, "argument %d: cannot use %s as %s", i+1, names[0], names[1])
+		}
+	}
+	return fn.Return
+}
+
+//overloaded checks a call of a builtin against each of its signatures. When
+//the arguments are not known well enough to pick one, the result is the return
+//type the matching signatures agree on, or any.
+func (c *Checker) overloaded(call *ast.CallExpression, fn *Overloaded, args []Type) Type {
+	if fn.Receiver != nil {
+		args = append([]Type{fn.Receiver}, args...)
+	}
+
+	matched := []*Func{}
+	arityMatched := false
+	for _, alt := range fn.Alts {
+		inst := c.instantiate(alt).(*Func)
+		if len(args) != len(inst.Params) && !(inst.Variadic && len(args) >= len(inst.Params)-1) {
+			continue
+		}
+		arityMatched = true
+
+		ok := false
+		c.attempt(func() bool {
+			ok = c.unifyArgs(inst, args)
+			return false
+		})
+		if ok {
+			matched = append(matched, inst)
+		}
+	}
+
+	switch {
+	case !arityMatched:
+		offset := 0
+		if fn.Receiver != nil {
+			offset = 1
+		}
+		c.errorf(call.Token, "wrong number of arguments to `%s`. got=%d, want=%d",
+			fn.Name, len(args)-offset, len(fn.Alts[0].Params)-offset)
+		return Any
+	case len(matched) == 0:
+		c.errorf(call.Token, "argument to `%s` not supported, got %s",
+			fn.Name, strings.Join(Strings(args...), ", "))
+		return Any
+	case len(matched) == 1 || !c.hasVars(args...):
+		c.unifyArgs(matched[0], args)
+		return matched[0].Return
+	}
+
+	ret, ok := prune(matched[0].Return).(*Basic)
+	for _, alt := range matched[1:] {
+		if prune(alt.Return) != ret {
+			ok = false
+		}
+	}
+	if !ok {
+		return Any
+	}
+	return ret
+}
+
+func (c *Checker) unifyArgs(fn *Func, args []Type) bool {
+	for i, arg := range args {
+		param := fn.Params[len(fn.Params)-1]
+		if i < len(fn.Params) {
+			param = fn.Params[i]
+		}
+		if !c.unifyTypes(param, arg) {
+			return false
+		}
+	}
+	return true
+}
+
+//member infers obj.name. For a call, methods of the builtin types are bound
+//to their receiver.
+func (c *Checker) member(me *ast.MemberExpression, call bool) Type {
+	recv := c.expr(me.Object)
+	name := me.Property.Value
+
+	kind := ""
+	switch r := prune(recv).(type) {
+	case *Hash:
+		return r.Value
+	case *Array:
+		kind = "array"
+	case *Basic:
+		if r == Any {
+			return Any
+		}
+		kind = r.Name
+	case *Var:
+		return Any
+	}
+
+	if alts, ok := methods[kind][name]; ok {
+		return &Overloaded{Name: name, Alts: alts, Receiver: recv}
+	}
+
+	if call {
+		c.errorf(me.Token, "undefined method %s for %s", name, recv)
+	} else {
+		c.errorf(me.Token, "undefined member %s for %s", name, recv)
+	}
+	return Any
+}
+
+func (c *Checker) index(ie *ast.IndexExpression) Type {
+	left := c.expr(ie.Left)
+	index := c.expr(ie.Index)
+
+	switch l := prune(left).(type) {
+	case *Array:
+		if !c.unify(index, Int) {
+			c.errorf(ie.Token, "array index must be int, got %s", index)
+		}
+		return l.Elem
+	case *Hash:
+		if !isHashable(index) {
+			c.errorf(ie.Token, "unusable as hash key: %s", index)
+		}
+		return l.Value
+	case *Var:
+		return Any
+	case *Basic:
+		switch l {
+		case Any:
+			return Any
+		case Range:
+			if !c.unify(index, Int) {
+				c.errorf(ie.Token, "range index must be int, got %s", index)
+			}
+			return Int
+		}
+	}
+
+	c.errorf(ie.Token, "index operator not supported: %s", left)
+	return Any
+}
+
+func (c *Checker) hash(hl *ast.HashLiteral) Type {
+	key := Type(c.newVar())
+	value := Type(c.newVar())
+
+	for _, pair := range hl.Pairs {
+		k := c.expr(pair.Key)
+		if !isHashable(k) {
+			c.errorf(hl.Token, "unusable as hash key: %s", k)
+		}
+		key = c.join(key, k)
+		value = c.join(value, c.expr(pair.Value))
+	}
+
+	return &Hash{Key: key, Value: value}
+}
+
+//try gives the catch variable the type of the hash errors are caught as
+func (c *Checker) try(te *ast.TryExpression) Type {
+	t := c.block(te.Block)
+
+	if te.Catch != nil {
+		c.openScope()
+		c.scope.names[te.CatchParam.Value] = &Hash{Key: String, Value: Any}
+		t = c.join(t, c.block(te.Catch))
+		c.closeScope()
+	}
+
+	if te.Finally != nil {
+		c.block(te.Finally)
+	}
+	return t
+}
+
+//join returns a type both x and y have, unifying them if possible, or any
+func (c *Checker) join(x, y Type) Type {
+	if prune(x) == Any || prune(y) == Any || !c.unify(x, y) {
+		return Any
+	}
+	return x
+}
+
+//unify makes x and y the same type, and reports whether that is possible. On
+//failure both are left as they were.
+func (c *Checker) unify(x, y Type) bool {
+	return c.attempt(func() bool { return c.unifyTypes(x, y) })
+}
+
+//compatible reports whether x and y could be unified, without unifying them
+func (c *Checker) compatible(x, y Type) bool {
+	ok := false
+	c.attempt(func() bool {
+		ok = c.unifyTypes(x, y)
+		return false
+	})
+	return ok
+}
+
+//attempt runs f and undoes the variables it bound if it returns false
+func (c *Checker) attempt(f func() bool) bool {
+	mark := len(c.trail)
+	outer := c.trying
+	c.trying = true
+
+	ok := f()
+	if !ok {
+		for i := len(c.trail) - 1; i >= mark; i-- {
+			u := c.trail[i]
+			u.v.instance, u.v.level = u.instance, u.level
+		}
+		c.trail = c.trail[:mark]
+	}
+
+	c.trying = outer
+	if !outer {
+		c.trail = c.trail[:0]
+	}
+	return ok
+}
+
+func (c *Checker) set(v *Var, instance Type, level int) {
+	if c.trying {
+		c.trail = append(c.trail, undo{v: v, instance: v.instance, level: v.level})
+	}
+	v.instance, v.level = instance, level
+}
+
+func (c *Checker) unifyTypes(x, y Type) bool {
+	x, y = prune(x), prune(y)
+	if x == y || x == Any || y == Any {
+		return true
+	}
+	if v, ok := x.(*Var); ok {
+		return c.bind(v, y)
+	}
+	if v, ok := y.(*Var); ok {
+		return c.bind(v, x)
+	}
+
+	// builtins are only checked when called
+	_, xo := x.(*Overloaded)
+	_, yo := y.(*Overloaded)
+	if xo || yo {
+		_, xf := x.(*Func)
+		_, yf := y.(*Func)
+		return xo && yo || xo && yf || xf && yo
+	}
+
+	switch x := x.(type) {
+	case *Array:
+		y, ok := y.(*Array)
+		return ok && c.unifyTypes(x.Elem, y.Elem)
+	case *Hash:
+		y, ok := y.(*Hash)
+		return ok && c.unifyTypes(x.Key, y.Key) && c.unifyTypes(x.Value, y.Value)
+	case *Func:
+		y, ok := y.(*Func)
+		if !ok || len(x.Params) != len(y.Params) {
+			return false
+		}
+		for i := range x.Params {
+			if !c.unifyTypes(x.Params[i], y.Params[i]) {
+				return false
+			}
+		}
+		return c.unifyTypes(x.Return, y.Return)
+	}
+	return false
+}
+
+//bind makes v stand for t. A type containing v itself, as for fn(f) { f(f) },
+//cannot be written down and is left unchecked.
+func (c *Checker) bind(v *Var, t Type) bool {
+	if c.occurs(v, t) {
+		return true
+	}
+	c.adjustLevels(t, v.level)
+	c.set(v, t, v.level)
+	return true
+}
+
+func (c *Checker) occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Array:
+		return c.occurs(v, t.Elem)
+	case *Hash:
+		return c.occurs(v, t.Key) || c.occurs(v, t.Value)
+	case *Func:
+		for _, param := range t.Params {
+			if c.occurs(v, param) {
+				return true
+			}
+		}
+		return c.occurs(v, t.Return)
+	}
+	return false
+}
+
+//adjustLevels keeps the variables of t from being generalized at a deeper
+//level than the variable t is bound to
+func (c *Checker) adjustLevels(t Type, level int) {
+	c.walkVars(t, func(v *Var) {
+		if v.level > level {
+			c.set(v, nil, level)
+		}
+	})
+}
+
+func (c *Checker) walkVars(t Type, f func(*Var)) {
+	switch t := prune(t).(type) {
+	case *Var:
+		f(t)
+	case *Array:
+		c.walkVars(t.Elem, f)
+	case *Hash:
+		c.walkVars(t.Key, f)
+		c.walkVars(t.Value, f)
+	case *Func:
+		for _, param := range t.Params {
+			c.walkVars(param, f)
+		}
+		c.walkVars(t.Return, f)
+	}
+}
+
+func (c *Checker) hasVars(ts ...Type) bool {
+	found := false
+	for _, t := range ts {
+		c.walkVars(t, func(*Var) { found = true })
+	}
+	return found
+}
+
+//generalize turns the variables of t created inside the current let into
+//variables of a type scheme
+func (c *Checker) generalize(t Type) Type {
+	c.walkVars(t, func(v *Var) {
+		if v.level > c.level {
+			v.level = generic
+		}
+	})
+	return t
+}
+
+//instantiate copies t with fresh variables for the variables of its scheme
+func (c *Checker) instantiate(t Type) Type {
+	return c.copyType(t, make(map[*Var]*Var))
+}
+
+func (c *Checker) copyType(t Type, fresh map[*Var]*Var) Type {
+	switch t := prune(t).(type) {
+	case *Var:
+		if t.level != generic {
+			return t
+		}
+		if v, ok := fresh[t]; ok {
+			return v
+		}
+		v := c.newVar()
+		fresh[t] = v
+		return v
+	case *Array:
+		return &Array{Elem: c.copyType(t.Elem, fresh)}
+	case *Hash:
+		return &Hash{Key: c.copyType(t.Key, fresh), Value: c.copyType(t.Value, fresh)}
+	case *Func:
+		params := []Type{}
+		for _, param := range t.Params {
+			params = append(params, c.copyType(param, fresh))
+		}
+		return &Func{Params: params, Return: c.copyType(t.Return, fresh), Variadic: t.Variadic}
+	}
+	return t
+}
@@ -0,0 +1,188 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/literallystan/go-terpreter/ast"
+	"github.com/literallystan/go-terpreter/lexer"
+	"github.com/literallystan/go-terpreter/parser"
+)
+
+func check(t *testing.T, input string) (*Checker, *ast.Program) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	c := New()
+	c.Check(program)
+	return c, program
+}
+
+//lastType returns the type of the last expression statement of program
+func lastType(t *testing.T, c *Checker, program *ast.Program) string {
+	stmt, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("last statement is not an *ast.ExpressionStatement. got=%T",
+			program.Statements[len(program.Statements)-1])
+	}
+	return c.TypeOf(stmt.Expression).String()
+}
+
+func TestInferredTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5", "int"},
+		{"true", "bool"},
+		{`"a"`, "string"},
+		{"1 + 2 * 3", "int"},
+		{`"a" + "b"`, "string"},
+		{"1 < 2", "bool"},
+		{`"a" < "b"`, "bool"},
+		{"[1, 2] > [1]", "bool"},
+		{"fn(a, b) { [a] < [b] }", "fn(a, a) -> bool"},
+		{`[1] == ["a"]`, "bool"},
+		{"!5", "bool"},
+		{"-5", "int"},
+		{"[1, 2]", "[int]"},
+		{"[]", "[a]"},
+		{`[1, "a"]`, "[any]"},
+		{`{"a": 1}`, "{string: int}"},
+		{`{"a": 1, "b": true}`, "{string: any}"},
+		{"1..10", "range"},
+		{"fn(x) { x }", "fn(a) -> a"},
+		{"fn(x, y) { x + y * 2 }", "fn(int, int) -> int"},
+		{`fn(s) { s + "!" }`, "fn(string) -> string"},
+		{"fn(a, b) { a + b }", "fn(a, a) -> a"},
+		{"fn(f, x) { f(f(x)) }", "fn(fn(a) -> a, a) -> a"},
+		{"fn(x) { if (x) { 1 } else { 2 } }", "fn(a) -> int"},
+		{`fn(x) { if (x) { 1 } else { "a" } }`, "fn(a) -> any"},
+		{"fn(x) { if (x) { 1 } }", "fn(a) -> any"},
+		{"fn(x) { if (x) { return 1 }; 2 }", "fn(a) -> int"},
+		{"fn(a) { a[0] }", "fn(a) -> any"},
+		{"let id = fn(x) { x }; id(5)", "int"},
+		{`let id = fn(x) { x }; id(5); id("a")`, "string"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact", "fn(int) -> int"},
+		{"let a = [1, 2]; a[0]", "int"},
+		{`let h = {"a": "b"}; h["a"]`, "string"},
+		{`let h = {"a": "b"}; h.a`, "string"},
+		{"len([1])", "int"},
+		{"fn(x) { len(x) }", "fn(a) -> int"},
+		{"first([true])", "bool"},
+		{"first(1..3)", "int"},
+		{"push([1], 2)", "[int]"},
+		{`push([1], "a")`, "[any]"},
+		{"array(1..3)", "[int]"},
+		{`"a".upper()`, "string"},
+		{"[1, 2].tail()", "[int]"},
+		{"print(1, 2)", "null"},
+		{`try { 1 } catch (e) { e["message"] }`, "any"},
+		{`try { "a" } catch (e) { e["message"] }`, "any"},
+		{`import "x"`, "any"},
+		{"undefinedName", "any"},
+		{"fn(name: string, count: int) -> array { [] }", "fn(string, int) -> [a]"},
+		{"fn(x, f: fn) { f(x) }", "fn(a, any) -> any"},
+		{"let x: int = 5; x", "int"},
+	}
+
+	for _, tt := range tests {
+		c, program := check(t, tt.input)
+		if len(c.Errors()) != 0 {
+			t.Errorf("unexpected errors for %q: %v", tt.input, c.Errors())
+			continue
+		}
+		if got := lastType(t, c, program); got != tt.expected {
+			t.Errorf("wrong type for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true", "1:3: type mismatch: int + bool"},
+		{"true + false", "1:6: unknown operator: bool + bool"},
+		{`"a" - "b"`, "1:5: unknown operator: string - string"},
+		{`1 < "a"`, "1:3: type mismatch: int < string"},
+		{`[1] < ["a"]`, "1:5: type mismatch: [int] < [string]"},
+		{"true < false", "1:6: unknown operator: bool < bool"},
+		{"-true", "1:1: unknown operator: -bool"},
+		{"len(5)", "1:4: argument to `len` not supported, got int"},
+		{"len()", "1:4: wrong number of arguments to `len`. got=0, want=1"},
+		{`"a".upper(1)`, "1:10: wrong number of arguments to `upper`. got=1, want=0"},
+		{"first(true)", "1:6: argument to `first` not supported, got bool"},
+		{"fn(x) { x + 1 }(true)", "1:16: argument 1: cannot use bool as int"},
+		{"let f = fn(x, y) { x }; f(1)", "1:26: wrong number of arguments. got=1, want=2"},
+		{"let add = fn(a, b) { a + b }; add(1, true)", "1:34: argument 2: cannot use bool as int"},
+		{"fn(x) { x + true }", "1:11: unknown operator: a + bool"},
+		{"5(1)", "1:2: not a function: int"},
+		{"let x = 5; x()", "1:13: not a function: int"},
+		{"[1][true]", "1:4: array index must be int, got bool"},
+		{"5[0]", "1:2: index operator not supported: int"},
+		{"{}[[1]]", "1:3: unusable as hash key: [int]"},
+		{"{[1]: 2}", "1:1: unusable as hash key: [int]"},
+		{`1.."a"`, "1:2: range bound must be int, got string"},
+		{"let n = 5; n.foo", "1:13: undefined member foo for int"},
+		{`"a".foo()`, "1:4: undefined method foo for string"},
+		{"let f = fn(g) { g(1) }; f(fn(s) { s + \"a\" })", "1:26: argument 1: cannot use fn(string) -> string as fn(int) -> a"},
+		{`let x: int = "a";`, "1:8: value of x must be int, got string"},
+		{`fn(s: string) { s * 2 }`, "1:19: type mismatch: string * int"},
+		{`fn(x) -> int { "a" }`, "1:10: return value must be int, got string"},
+		{`let f = fn(n: int) { n }; f("a")`, "1:28: argument 1: cannot use string as int"},
+	}
+
+	for _, tt := range tests {
+		c, _ := check(t, tt.input)
+		if len(c.Errors()) != 1 {
+			t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expected, c.Errors())
+			continue
+		}
+		if c.Errors()[0] != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, c.Errors()[0])
+		}
+	}
+}
+
+func TestDynamicCodeIsAccepted(t *testing.T) {
+	inputs := []string{
+		`let xs = [1, "a", true]; first(xs) + 1`,
+		`let person = {"name": "ann", "age": 3}; person["age"] + 1`,
+		`let r = if (true) { 1 } else { "a" }; r + 1`,
+		`let f = fn(x) { x }; f(1) + f(2); f("a") + f("b")`,
+		`let apply = fn(f, x) { f(x) }; apply(fn(n) { n + 1 }, 1); apply(len, "ab")`,
+		`let m = import "m"; m.f(1) + 2`,
+		`let self_apply = fn(f) { f(f) };`,
+		`let obj = {"n": 1, "get": fn() { self.n }}; obj.get()`,
+		`try { throw "x" } catch (e) { e["message"] + "!" }`,
+		`contains([1, 2], "a"); contains("ab", "a"); contains({"a": 1}, "a"); contains(1..3, 2)`,
+		`print("a", 1, true)`,
+		`doc(len)`,
+	}
+
+	for _, input := range inputs {
+		c, _ := check(t, input)
+		if len(c.Errors()) != 0 {
+			t.Errorf("unexpected errors for %q: %v", input, c.Errors())
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	v := &Var{id: 1}
+	w := &Var{id: 2}
+
+	got := Strings(&Func{Params: []Type{v, w}, Return: v}, w)
+	if got[0] != "fn(a, b) -> a" || got[1] != "b" {
+		t.Errorf("wrong strings. got=%q", got)
+	}
+
+	variadic := &Func{Params: []Type{Any}, Return: Null, Variadic: true}
+	if variadic.String() != "fn(any...) -> null" {
+		t.Errorf("wrong string. got=%q", variadic.String())
+	}
+}
@@ -0,0 +1,151 @@
+package types
+
+import "strings"
+
+//Type is the static type of an expression
+type Type interface {
+	String() string
+	typeNode()
+}
+
+//Basic is a type without parts, such as int
+type Basic struct {
+	Name string
+}
+
+//The basic types. Any is the type of values the checker knows nothing about,
+//such as the result of an import. It is compatible with every type.
+var (
+	Int    = &Basic{Name: "int"}
+	Bool   = &Basic{Name: "bool"}
+	String = &Basic{Name: "string"}
+	Null   = &Basic{Name: "null"}
+	Range  = &Basic{Name: "range"}
+	Any    = &Basic{Name: "any"}
+)
+
+//Array is the type of arrays whose elements are all of type Elem
+type Array struct {
+	Elem Type
+}
+
+//Hash is the type of hashes with keys of type Key and values of type Value
+type Hash struct {
+	Key, Value Type
+}
+
+//Func is the type of functions. A Variadic function takes any number of
+//arguments of the type of its last parameter.
+type Func struct {
+	Params   []Type
+	Return   Type
+	Variadic bool
+}
+
+//Var is a type not inferred yet. Once unified with another type it stands for
+//that instance.
+type Var struct {
+	id       int
+	level    int
+	instance Type
+}
+
+//Overloaded is the type of a builtin that accepts several signatures, such as
+//len, which takes an array, a string or a range. Receiver is set for a method
+//and passed as the first argument.
+type Overloaded struct {
+	Name     string
+	Alts     []*Func
+	Receiver Type
+}
+
+func (b *Basic) typeNode()      {}
+func (a *Array) typeNode()      {}
+func (h *Hash) typeNode()       {}
+func (f *Func) typeNode()       {}
+func (v *Var) typeNode()        {}
+func (o *Overloaded) typeNode() {}
+
+func (b *Basic) String() string      { return Strings(b)[0] }
+func (a *Array) String() string      { return Strings(a)[0] }
+func (h *Hash) String() string       { return Strings(h)[0] }
+func (f *Func) String() string       { return Strings(f)[0] }
+func (v *Var) String() string        { return Strings(v)[0] }
+func (o *Overloaded) String() string { return Strings(o)[0] }
+
+//prune returns the type t stands for, following the instances of variables
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.instance == nil {
+			return t
+		}
+		t = v.instance
+	}
+}
+
+//Strings formats types for one message, naming their variables a, b, c...
+//in order of appearance, so that the same variable has the same name in each
+func Strings(ts ...Type) []string {
+	p := &printer{names: make(map[*Var]string)}
+
+	out := []string{}
+	for _, t := range ts {
+		var b strings.Builder
+		p.write(&b, t)
+		out = append(out, b.String())
+	}
+	return out
+}
+
+type printer struct {
+	names map[*Var]string
+}
+
+func (p *printer) name(v *Var) string {
+	if name, ok := p.names[v]; ok {
+		return name
+	}
+
+	n := len(p.names)
+	name := string(rune('a' + n%26))
+	if n >= 26 {
+		name += strings.Repeat("'", n/26)
+	}
+	p.names[v] = name
+	return name
+}
+
+func (p *printer) write(b *strings.Builder, t Type) {
+	switch t := prune(t).(type) {
+	case *Basic:
+		b.WriteString(t.Name)
+	case *Var:
+		b.WriteString(p.name(t))
+	case *Array:
+		b.WriteString("[")
+		p.write(b, t.Elem)
+		b.WriteString("]")
+	case *Hash:
+		b.WriteString("{")
+		p.write(b, t.Key)
+		b.WriteString(": ")
+		p.write(b, t.Value)
+		b.WriteString("}")
+	case *Func:
+		b.WriteString("fn(")
+		for i, param := range t.Params {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			p.write(b, param)
+		}
+		if t.Variadic {
+			b.WriteString("...")
+		}
+		b.WriteString(") -> ")
+		p.write(b, t.Return)
+	case *Overloaded:
+		b.WriteString("builtin " + t.Name)
+	}
+}